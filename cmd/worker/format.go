@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/zxdev/client/worker/client"
+	"github.com/zxdev/client/worker/job"
+)
+
+// kind is a job type with its csv and table columns
+type kind struct {
+	job    func(string) client.Job
+	header []string
+	row    func(client.Job) []string
+}
+
+// kinds are the supported job types by worker.Path
+var kinds = map[string]kind{
+	"title": {
+		job:    func(a string) client.Job { return job.NewTitle(a) },
+		header: []string{"url", "status", "title"},
+		row: func(j client.Job) []string {
+			r := j.Unpack().(job.Title)
+			return []string{r.Url, itoa(r.Status), r.Title}
+		},
+	},
+	"dns": {
+		job:    func(a string) client.Job { return job.NewDNS(a) },
+		header: []string{"host", "status", "rcode", "a", "aaaa", "cname", "ns", "mx", "txt", "domain"},
+		row: func(j client.Job) []string {
+			r := j.Unpack().(job.DNS)
+			return []string{r.Host, itoa(r.Status), join(job.DNSDecode(&r.RCode)),
+				join(r.A), join(r.AAAA), join(r.CNAME), join(r.NS), join(r.MX), join(r.TXT), join(r.Domain)}
+		},
+	},
+	"rdap": {
+		job:    func(a string) client.Job { return job.NewRdap(a) },
+		header: []string{"host", "status", "nrd", "nameserver"},
+		row: func(j client.Job) []string {
+			r := j.Unpack().(job.Rdap)
+			return []string{r.Host, itoa(r.Status), strconv.FormatBool(r.NRD), join(r.NameServer)}
+		},
+	},
+	"method": {
+		job:    func(a string) client.Job { return job.NewMethod(a) },
+		header: []string{"url", "status", "standard", "methods"},
+		row: func(j client.Job) []string {
+			r := j.Unpack().(job.Method)
+			return []string{r.Url, itoa(r.Status), strconv.FormatBool(job.MethodStandard(&r.Flag)),
+				join(job.MethodDecoder(&r.Flag, &r.Options))}
+		},
+	},
+	"hval": {
+		job:    func(a string) client.Job { return job.NewHval(a) },
+		header: []string{"item", "status", "hops", "basic", "security"},
+		row: func(j client.Job) []string {
+			r := j.Unpack().(job.Hval)
+			return []string{r.Item, itoa(r.Status), itoa(r.N), strconv.FormatBool(job.SecurityBasic(&r.Security)),
+				join(job.SecurityDecoder(&r.Security))}
+		},
+	},
+	"cert": {
+		job:    func(a string) client.Job { return job.NewCert(a) },
+		header: []string{"host", "status", "tls", "cipher", "verified", "hostname", "subject", "issuer", "expires"},
+		row: func(j client.Job) []string {
+			r := j.Unpack().(job.Cert)
+			row := []string{r.Host, itoa(r.Status), "", "", "", "", "", "", ""}
+			if c := r.Connection; c != nil {
+				row[2], row[3] = c.TLSVersion, c.CipherSuite
+			}
+			if v := r.Verification; v != nil {
+				row[4], row[5] = strconv.FormatBool(v.ChainVerified), strconv.FormatBool(v.HostnameMatches)
+			}
+			if len(r.Certs) > 0 {
+				row[6], row[7], row[8] = r.Certs[0].SubjectCN, r.Certs[0].IssuerCN, r.Certs[0].NotAfter
+			}
+			return row
+		},
+	},
+	"mail": {
+		job:    func(a string) client.Job { return job.NewMail(a) },
+		header: []string{"host", "status", "rcode", "mx", "spf", "dmarc"},
+		row: func(j client.Job) []string {
+			r := j.Unpack().(job.Mail)
+			return []string{r.Host, itoa(r.Status), join(job.MailDecode(&r.RCode)), join(r.MX), join(r.Spf), join(r.Dmarc)}
+		},
+	},
+	"crtsh": {
+		job:    func(a string) client.Job { return job.NewCRTSH(a) },
+		header: []string{"host", "status", "count"},
+		row: func(j client.Job) []string {
+			r := j.Unpack().(job.CRTSH)
+			return []string{r.Host, itoa(r.Status), itoa(r.Count)}
+		},
+	},
+	"firewall": {
+		job:    func(a string) client.Job { return job.NewFirewall(a) },
+		header: []string{"host", "status", "block", "ip", "version"},
+		row: func(j client.Job) []string {
+			r := j.Unpack().(job.Firewall)
+			return []string{r.Host, itoa(r.Status), strconv.FormatBool(r.Block), join(r.IP), strconv.FormatInt(r.Version, 10)}
+		},
+	},
+}
+
+// names returns the sorted job type names
+func names() []string {
+	var list []string
+	for name := range kinds {
+		list = append(list, name)
+	}
+	slices.Sort(list)
+	return list
+}
+
+func itoa(n int) string         { return strconv.Itoa(n) }
+func join(list []string) string { return strings.Join(list, ",") }
+
+// writer writes the result jobs in an output format
+type writer struct {
+	write func(client.Job) error
+	flush func() error
+}
+
+// newWriter returns the json, csv, or table writer for the job kind
+func newWriter(format string, w io.Writer, k kind) (*writer, error) {
+
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		return &writer{
+			write: func(j client.Job) error { return enc.Encode(j.Unpack()) },
+			flush: func() error { return nil },
+		}, nil
+
+	case "csv":
+		cw := csv.NewWriter(w)
+		cw.Write(k.header)
+		return &writer{
+			write: func(j client.Job) error { return cw.Write(k.row(j)) },
+			flush: func() error { cw.Flush(); return cw.Error() },
+		}, nil
+
+	case "table":
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.ToUpper(strings.Join(k.header, "\t")))
+		return &writer{
+			write: func(j client.Job) error {
+				_, err := fmt.Fprintln(tw, strings.Join(k.row(j), "\t"))
+				return err
+			},
+			flush: tw.Flush,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("unknown format %q", format)
+}
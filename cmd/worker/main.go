@@ -0,0 +1,155 @@
+// Command worker queries the worker cluster from the command line
+//
+//	worker [flags] {title|dns|rdap|method|hval|cert|mail|crtsh|firewall} [host ...]
+//
+// Hosts are read from the arguments, the -file list, or stdin when neither
+// is given, one per line; results are written as JSON lines, CSV, or a table.
+// The worker is configured by the -conf file, with the job type profile, and
+// the WORKER_ variables of client.LoadConfig while the flags take precedence.
+//
+//	echo zxdev.com | worker -format table dns
+//	worker -size 50 -workers 4 -format csv -file hosts.txt rdap > rdap.csv
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/zxdev/client/worker/client"
+)
+
+func main() {
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	err := run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr)
+	switch {
+	case errors.Is(err, flag.ErrHelp):
+	case err != nil:
+		fmt.Fprintln(os.Stderr, "worker:", err)
+		os.Exit(2)
+	}
+}
+
+// run executes the command line; per job failures are reported on stderr
+// and in the output while configuration failures are returned
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+
+	var flags client.Worker
+	var conf, file, format string
+
+	fs := flag.NewFlagSet("worker", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: worker [flags] {"+strings.Join(names(), "|")+"} [host ...]")
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&conf, "conf", "", "json, yaml, or toml config file; see client.LoadConfig")
+	fs.StringVar(&flags.Host, "host", "", "cluster host scheme://host:port")
+	fs.StringVar(&flags.Secret, "secret", "", "passkey secret")
+	fs.IntVar(&flags.Size, "size", 0, "POST batch size; 1 with -full, GET otherwise")
+	fs.IntVar(&flags.Workers, "workers", 0, "number of workers (default 10)")
+	fs.DurationVar(&flags.Pacer, "pacer", 0, "pacer delay between requests (default 10ms)")
+	fs.StringVar(&flags.Params, "params", "", "endpoint ?param segment, such as ?full=true")
+	fs.BoolVar(&flags.FullURL, "full", false, "POST full urls instead of GET by host")
+	fs.StringVar(&file, "file", "", "host list file; default args or stdin")
+	fs.StringVar(&format, "format", "json", "output format: json (lines), csv, or table")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errors.New("job type required")
+	}
+	kind, ok := kinds[fs.Arg(0)]
+	if !ok {
+		return fmt.Errorf("unknown job type %q", fs.Arg(0))
+	}
+	out, err := newWriter(format, stdout, kind)
+	if err != nil {
+		return err
+	}
+
+	// the config file and WORKER_ variables with the flags taking precedence
+	work, err := client.LoadConfig(conf, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "host":
+			work.Host = flags.Host
+		case "secret":
+			work.Secret = flags.Secret
+		case "size":
+			work.Size = flags.Size
+		case "workers":
+			work.Workers = flags.Workers
+		case "pacer":
+			work.Pacer = flags.Pacer
+		case "params":
+			work.Params = flags.Params
+		case "full":
+			work.FullURL = flags.FullURL
+		}
+	})
+
+	var hosts io.Reader
+	switch {
+	case fs.NArg() > 1:
+		hosts = strings.NewReader(strings.Join(fs.Args()[1:], "\n"))
+	case len(file) > 0:
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		hosts = f
+	default:
+		hosts = stdin
+	}
+
+	work.Connect(ctx)
+
+	var rerr error
+	go func() {
+		defer work.Done()
+		scan := bufio.NewScanner(hosts)
+		for scan.Scan() {
+			host := strings.TrimSpace(scan.Text())
+			if len(host) == 0 || strings.HasPrefix(host, "#") {
+				continue
+			}
+			if err := work.Submit(ctx, kind.job(host)); err != nil {
+				return
+			}
+		}
+		rerr = scan.Err()
+	}()
+
+	for j := range work.Outbox {
+		if err := j.Err(); err != nil {
+			fmt.Fprintln(stderr, "worker:", err)
+		}
+		if err := out.write(j); err != nil {
+			return err
+		}
+	}
+	if err := out.flush(); err != nil {
+		return err
+	}
+
+	if rerr == nil && ctx.Err() != nil {
+		rerr = ctx.Err()
+	}
+	return rerr
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zxdev/client/worker/fake"
+	"github.com/zxdev/client/worker/job"
+)
+
+const secret = "NETSTARXNETSTARXXNETSTARXNETSTAR"
+
+// go test -v ./cmd/worker --run=CLI
+func TestCLI(t *testing.T) {
+
+	srv := (&fake.Server{
+		Secret: secret,
+		Respond: func(path, key string) any {
+			return job.DNS{RCode: job.A | job.MX, A: []string{"10.0.0.1"}, MX: []string{"mx." + key}}
+		},
+	}).Start(t.Context())
+	defer srv.Close()
+
+	file := filepath.Join(t.TempDir(), "hosts.txt")
+	os.WriteFile(file, []byte("# hosts\none.com\n\ntwo.com\n"), 0644)
+
+	cli := func(stdin string, args ...string) string {
+		var stdout, stderr bytes.Buffer
+		args = append([]string{"-host", srv.URL, "-secret", secret}, args...)
+		if err := run(t.Context(), args, strings.NewReader(stdin), &stdout, &stderr); err != nil {
+			t.Fatal(err, stderr.String())
+		}
+		return stdout.String()
+	}
+
+	// json lines from args by GET
+	lines := strings.Split(strings.TrimSpace(cli("", "dns", "one.com", "two.com")), "\n")
+	if len(lines) != 2 {
+		t.Fatal("expected two json lines", lines)
+	}
+	var r job.DNS
+	if err := json.Unmarshal([]byte(lines[0]), &r); err != nil || r.A[0] != "10.0.0.1" {
+		t.Fatal("expected dns result", lines[0], err)
+	}
+
+	// csv from a file by POST batches
+	records, err := csv.NewReader(strings.NewReader(cli("", "-size", "5", "-format", "csv", "-file", file, "dns"))).ReadAll()
+	if err != nil || len(records) != 3 || records[0][2] != "rcode" || records[1][2] != "A,MX" {
+		t.Fatal("expected csv with decoded rcode", records, err)
+	}
+
+	// table from stdin
+	table := cli("one.com\n", "-format", "table", "-pacer", "1ms", "-params", "?x=1", "dns")
+	if !strings.HasPrefix(table, "HOST") || !strings.Contains(table, "mx.one.com") {
+		t.Fatal("expected table", table)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if err := run(t.Context(), []string{"whois"}, nil, &stdout, &stderr); err == nil {
+		t.Fatal("expected unknown job type")
+	}
+	if err := run(t.Context(), []string{"-format", "xml", "dns"}, nil, &stdout, &stderr); err == nil {
+		t.Fatal("expected unknown format")
+	}
+}
@@ -1,5 +1,13 @@
 module github.com/zxdev/client
 
-go 1.24
+go 1.24.0
 
-require github.com/zxdev/passkey v1.0.4
+require (
+	github.com/BurntSushi/toml v1.6.0
+	github.com/klauspost/compress v1.19.2
+	github.com/zxdev/passkey v1.0.4
+	golang.org/x/net v0.50.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require golang.org/x/text v0.34.0 // indirect
@@ -0,0 +1,74 @@
+package client
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+)
+
+const (
+	// POST request body encodings
+	BodyText   = iota // \n delimited Request() items; default
+	BodyJSON          // json array of job payloads
+	BodyNDJSON        // \n delimited json job payloads
+)
+
+// Payload is an optional Job extension for jobs that provide their own
+// POST request item, such as job.CertJob or job.RdapJob, to request per
+// item options; jobs without a Payload are marshaled as themselves in the
+// BodyJSON and BodyNDJSON modes
+type Payload interface {
+	Payload() any // json request item
+}
+
+// payload returns the json request item for job
+func payload(job Job) any {
+	if p, ok := job.(Payload); ok {
+		return p.Payload()
+	}
+	return job
+}
+
+// contentType returns the request Content-Type for the body encoding
+func contentType(body int) string {
+	switch body {
+	case BodyJSON:
+		return "application/json"
+	case BodyNDJSON:
+		return "application/x-ndjson"
+	}
+	return "text/plain"
+}
+
+// encode writes the POST request body for jobs using the body encoding
+func encode(w io.Writer, body int, jobs Jobs) error {
+
+	switch body {
+
+	case BodyJSON:
+		items := make([]any, len(jobs))
+		for i := range jobs {
+			items[i] = payload(jobs[i])
+		}
+		return json.NewEncoder(w).Encode(items)
+
+	case BodyNDJSON:
+		enc := json.NewEncoder(w) // Encode appends \n
+		for i := range jobs {
+			if err := enc.Encode(payload(jobs[i])); err != nil {
+				return err
+			}
+		}
+
+	default:
+		bw := bufio.NewWriter(w)
+		for i := range jobs {
+			bw.WriteString(jobs[i].Request())
+			bw.WriteByte(10) // \n
+		}
+		return bw.Flush()
+
+	}
+
+	return nil
+}
@@ -0,0 +1,410 @@
+package client
+
+import (
+	"cmp"
+	"container/list"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CacheTTL is the default Cache result ttl by worker.Path; other paths use
+// one hour. Firewall results are additionally invalidated as soon as a
+// newer firewall Version is observed; see Versioned
+var CacheTTL = map[string]time.Duration{
+	"rdap":     time.Hour * 24,
+	"crtsh":    time.Hour * 24,
+	"hval":     time.Hour * 24,
+	"cert":     time.Hour * 6,
+	"title":    time.Hour,
+	"method":   time.Hour,
+	"firewall": time.Hour,
+	"mail":     time.Minute * 15,
+	"dns":      time.Minute * 5,
+}
+
+// Versioned is the optional Job interface for results that are only valid
+// for a data set version, such as job.Firewall; cached results with an older
+// version than the newest version received are treated as expired
+type Versioned interface {
+	Versioned() int64
+}
+
+// Store is the Cache storage backend; LRU and Disk are the built-in
+// implementations and expired values must not be returned by Get
+type Store interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte, expires time.Time)
+}
+
+// Cache configuration for the Worker results keyed by the worker.Path,
+// worker.Params, and job.Request(); a cached job is returned on the
+// worker.Outbox without a request and a Cache is meant for a single Worker
+// while the Store may be shared
+//
+// Successful results use TTL while failures use the shorter Negative ttl;
+// only server reported failures and http 4xx status failures other than
+// 408 and 429 are cached, transport and 5xx failures never are
+type Cache struct {
+	Store    Store         // storage backend; default LRU
+	TTL      time.Duration // result ttl; default CacheTTL by worker.Path
+	Negative time.Duration // failure ttl; default 1-minute, <0 disables
+
+	latest atomic.Int64 // newest Versioned version received
+}
+
+// entry is the cached result
+type entry struct {
+	Job     json.RawMessage `json:"job"`
+	Version int64           `json:"version,omitempty"`
+	Status  int             `json:"status,omitempty"` // Job.Fail status of a client side failure
+	Method  string          `json:"method,omitempty"`
+	URL     string          `json:"url,omitempty"`
+	Code    int             `json:"code,omitempty"`
+	Err     string          `json:"err,omitempty"`
+}
+
+// configure applies the Cache default settings
+func (c *Cache) configure(path string) {
+	if c.Store == nil {
+		c.Store = new(LRU)
+	}
+	if c.TTL == 0 {
+		if c.TTL = CacheTTL[path]; c.TTL == 0 {
+			c.TTL = time.Hour
+		}
+	}
+	if c.Negative == 0 {
+		c.Negative = min(time.Minute, c.TTL)
+	}
+}
+
+// cacheKey returns the cache key for job from the path, params, request, and
+// the Payload request options without the UUID; the key is kept until the
+// job is fetched as the response may change the request and payload fields
+func (w *Worker) cacheKey(job Job) string {
+
+	w.keyMu.Lock()
+	defer w.keyMu.Unlock()
+
+	if key, ok := w.keys[job]; ok {
+		return key
+	}
+	key := w.Path + w.Params + "|" + job.Request()
+	if p, ok := job.(Payload); ok {
+		b, _ := json.Marshal(p.Payload())
+		var options map[string]any
+		if json.Unmarshal(b, &options) == nil {
+			delete(options, "uuid")
+			b, _ = json.Marshal(options)
+		}
+		key += "|" + string(b)
+	}
+	if w.keys == nil {
+		w.keys = make(map[Job]string)
+	}
+	w.keys[job] = key
+
+	return key
+}
+
+// forget releases the kept cache keys of jobs
+func (w *Worker) forget(jobs ...Job) {
+	w.keyMu.Lock()
+	for i := range jobs {
+		delete(w.keys, jobs[i])
+	}
+	w.keyMu.Unlock()
+}
+
+// cached fills job from the worker.Cache and reports whether it was found
+func (w *Worker) cached(job Job) bool {
+
+	if w.Cache == nil {
+		return false
+	}
+
+	b, ok := w.Cache.Store.Get(w.cacheKey(job))
+	var e entry
+	if ok && json.Unmarshal(b, &e) == nil && (e.Version == 0 || e.Version >= w.Cache.latest.Load()) {
+		var id uint64
+		t, tracked := job.(Tracker)
+		if tracked {
+			id = t.ID()
+		}
+		if json.Unmarshal(e.Job, job) == nil {
+			if tracked {
+				t.SetID(id)
+			}
+			if len(e.Err) > 0 || e.Code > 0 {
+				err := &Error{Method: e.Method, URL: e.URL, StatusCode: e.Code}
+				if len(e.Err) > 0 {
+					err.Err = errors.New(e.Err)
+				}
+				job.Fail(e.Status, err)
+			}
+			w.count("worker_cache_total", 1, "result", "hit")
+			return true
+		}
+	}
+
+	w.count("worker_cache_total", 1, "result", "miss")
+	return false
+}
+
+// uncached fills the cached jobs and returns the remaining jobs
+func (w *Worker) uncached(jobs Jobs) Jobs {
+
+	if w.Cache == nil {
+		return jobs
+	}
+
+	var pending Jobs
+	for i := range jobs {
+		if !w.cached(jobs[i]) {
+			pending = append(pending, jobs[i])
+		}
+	}
+
+	return pending
+}
+
+// remember stores the cacheable job results in the worker.Cache
+func (w *Worker) remember(jobs ...Job) {
+
+	if w.Cache == nil {
+		return
+	}
+
+	for i := range jobs {
+		var e entry
+		ttl := w.Cache.TTL
+		if !jobs[i].Okay() {
+			var err *Error
+			if errors.As(jobs[i].Err(), &err) {
+				if err.StatusCode/100 != 4 || err.StatusCode == http.StatusRequestTimeout ||
+					err.StatusCode == http.StatusTooManyRequests {
+					continue
+				}
+				e.Status, e.Method, e.URL, e.Code = err.Status(), err.Method, err.URL, err.StatusCode
+				if err.Err != nil {
+					e.Err = err.Err.Error()
+				}
+			} else if jobs[i].Err() != nil {
+				continue
+			}
+			if ttl = w.Cache.Negative; ttl < 0 {
+				continue
+			}
+		}
+
+		if v, ok := jobs[i].(Versioned); ok {
+			e.Version = v.Versioned()
+			for {
+				latest := w.Cache.latest.Load()
+				if e.Version <= latest || w.Cache.latest.CompareAndSwap(latest, e.Version) {
+					break
+				}
+			}
+		}
+
+		var err error
+		if e.Job, err = json.Marshal(jobs[i]); err != nil {
+			continue
+		}
+		if b, err := json.Marshal(e); err == nil {
+			w.Cache.Store.Set(w.cacheKey(jobs[i]), b, time.Now().Add(ttl))
+		}
+	}
+}
+
+// LRU is the in-memory least recently used Store
+type LRU struct {
+	Size int // max entries; default 10000
+
+	mu    sync.Mutex
+	order *list.List // front is the most recently used
+	items map[string]*list.Element
+}
+
+// lruItem is the LRU list value
+type lruItem struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+func (l *LRU) Get(key string) ([]byte, bool) {
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	el, ok := l.items[key]
+	if !ok {
+		return nil, false
+	}
+	item := el.Value.(*lruItem)
+	if time.Now().After(item.expires) {
+		l.order.Remove(el)
+		delete(l.items, key)
+		return nil, false
+	}
+	l.order.MoveToFront(el)
+
+	return item.value, true
+}
+
+func (l *LRU) Set(key string, value []byte, expires time.Time) {
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.items == nil {
+		if l.Size == 0 {
+			l.Size = 10000
+		}
+		l.order = list.New()
+		l.items = make(map[string]*list.Element)
+	}
+
+	if el, ok := l.items[key]; ok {
+		el.Value = &lruItem{key: key, value: value, expires: expires}
+		l.order.MoveToFront(el)
+		return
+	}
+	l.items[key] = l.order.PushFront(&lruItem{key: key, value: value, expires: expires})
+	for l.order.Len() > l.Size {
+		el := l.order.Back()
+		l.order.Remove(el)
+		delete(l.items, el.Value.(*lruItem).key)
+	}
+}
+
+// Len returns the number of entries, including expired ones not yet evicted
+func (l *LRU) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.items)
+}
+
+// Disk is the on-disk Store using a file per key under Dir so that the
+// cache survives restarts; expired files are removed when read and by a
+// sweep of Dir in the background of Set at most every Sweep, which also
+// removes the files soonest to expire above Max so that a long run over
+// mostly unique keys cannot grow the directory without bound
+type Disk struct {
+	Dir   string        // cache directory; created when missing
+	Sweep time.Duration // expired file sweep interval; default 10m
+	Max   int           // files kept by the sweep; 0 for no limit
+
+	swept    atomic.Int64 // last sweep start in unix nanoseconds
+	sweeping atomic.Bool  // sweep in progress
+}
+
+// file returns the file for key
+func (d *Disk) file(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	name := hex.EncodeToString(sum[:])
+	return filepath.Join(d.Dir, name[:2], name)
+}
+
+func (d *Disk) Get(key string) ([]byte, bool) {
+
+	name := d.file(key)
+	b, err := os.ReadFile(name)
+	if err != nil || len(b) < 8 {
+		return nil, false
+	}
+	if time.Now().UnixNano() > int64(binary.BigEndian.Uint64(b)) {
+		os.Remove(name)
+		return nil, false
+	}
+
+	return b[8:], true
+}
+
+func (d *Disk) Set(key string, value []byte, expires time.Time) {
+
+	name := d.file(key)
+	if os.MkdirAll(filepath.Dir(name), 0o755) != nil {
+		return
+	}
+
+	// write and rename so that readers never see a partial file
+	f, err := os.CreateTemp(filepath.Dir(name), ".tmp*")
+	if err != nil {
+		return
+	}
+	var exp [8]byte
+	binary.BigEndian.PutUint64(exp[:], uint64(expires.UnixNano()))
+	_, err = f.Write(append(exp[:], value...))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil || os.Rename(f.Name(), name) != nil {
+		os.Remove(f.Name())
+	}
+
+	d.sweep()
+}
+
+// sweep starts the background removal of the expired files and of the files
+// soonest to expire above Max when the Sweep interval has elapsed
+func (d *Disk) sweep() {
+
+	interval := d.Sweep
+	if interval == 0 {
+		interval = time.Minute * 10
+	}
+	now := time.Now().UnixNano()
+	if last := d.swept.Load(); now-last < int64(interval) || !d.sweeping.CompareAndSwap(false, true) {
+		return
+	}
+	d.swept.Store(now)
+
+	go func() {
+		defer d.sweeping.Store(false)
+
+		type file struct {
+			name    string
+			expires int64
+		}
+		var files []file
+		filepath.WalkDir(d.Dir, func(name string, e os.DirEntry, err error) error {
+			if err != nil || e.IsDir() || strings.HasPrefix(e.Name(), ".tmp") {
+				return nil
+			}
+			f, err := os.Open(name)
+			if err != nil {
+				return nil
+			}
+			var exp [8]byte
+			_, err = io.ReadFull(f, exp[:])
+			f.Close()
+			if expires := int64(binary.BigEndian.Uint64(exp[:])); err != nil || now > expires {
+				os.Remove(name)
+			} else {
+				files = append(files, file{name, expires})
+			}
+			return nil
+		})
+
+		if d.Max > 0 && len(files) > d.Max {
+			slices.SortFunc(files, func(a, b file) int { return cmp.Compare(a.expires, b.expires) })
+			for _, f := range files[:len(files)-d.Max] {
+				os.Remove(f.name)
+			}
+		}
+	}()
+}
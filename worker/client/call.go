@@ -0,0 +1,68 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// Do is the blocking request/response alternative to the worker.Inbox and
+// worker.Outbox pipeline for one-off lookups; the job is filled using the
+// connected Worker auth, pacing, retry, and GET|POST settings and is
+// returned with the request failure, if any
+//
+//	r, err := work.Do(ctx, job.NewTitle("zxdev.com"))
+func (w *Worker) Do(ctx context.Context, job Job) (Job, error) {
+
+	if w.FullURL {
+		w.fetchBatch(ctx, Jobs{job}, nil)
+	} else {
+		w.fetch(ctx, job)
+	}
+
+	return job, job.Err()
+}
+
+// DoBatch is the blocking variant for multiple jobs and returns the filled
+// jobs keyed by their job.Request() as given, before any normalization,
+// along with the joined request failures; POST requests are batched by
+// worker.Size and at most worker.Workers requests are in flight at a time
+func (w *Worker) DoBatch(ctx context.Context, jobs Jobs) (map[string]Job, error) {
+
+	keys := make([]string, len(jobs))
+	for i := range jobs {
+		keys[i] = jobs[i].Request()
+	}
+
+	var batches []Jobs
+	for i := 0; i < len(jobs); i += w.Size {
+		batches = append(batches, jobs[i:min(i+w.Size, len(jobs))])
+	}
+
+	var wg sync.WaitGroup
+	limit := make(chan struct{}, w.Workers)
+	for i := range batches {
+		wg.Add(1)
+		limit <- struct{}{}
+		go func(batch Jobs) {
+			defer func() { <-limit; wg.Done() }()
+			if w.FullURL {
+				w.fetchBatch(ctx, batch, nil)
+			} else {
+				w.fetch(ctx, batch[0])
+			}
+		}(batches[i])
+	}
+	wg.Wait()
+
+	var errs []error
+	result := make(map[string]Job, len(jobs))
+	for i := range jobs {
+		result[keys[i]] = jobs[i]
+		if err := jobs[i].Err(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return result, errors.Join(errs...)
+}
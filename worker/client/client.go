@@ -4,9 +4,14 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"io"
 	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/zxdev/passkey"
@@ -14,9 +19,11 @@ import (
 
 // Job interface for GET Inbox/Outbox expectation and response
 type Job interface {
-	Request() string // request url|host key
-	Unpack() any     // unpack the job
-	Okay() bool      // job response result status
+	Request() string            // request url|host key
+	Unpack() any                // unpack the job
+	Okay() bool                 // job response result status
+	Err() error                 // request failure recorded by Fail; nil otherwise
+	Fail(status int, err error) // record a request failure and status on the job
 }
 
 // Jobs interface for POST Inbox/Outbox expectation and response
@@ -28,21 +35,59 @@ func (j *Jobs) Reset() { *j = []Job{} }
 // using generic types with auto selection of GET vs POST methods based on
 // the worker.Bulk value setting
 type Worker struct {
-	Host   string `json:"host,omitempty"`   // host: scheme://host:port
-	Secret string `json:"secret,omitempty"` // worker: passKey secret
-
-	Workers       int                 `json:"-"` // number of workers
-	Path          string              `json:"-"` // host: endpoint path segment
-	Params        string              `json:"-"` // host: endpoint ?param segment
-	AuthHeader    func(*http.Request) `json:"_"` // set the auth header
-	Client        *http.Client        `json:"-"` // client; default timeout 10-second
-	Pacer         time.Duration       `json:"-"` // pacer time delay
-	Size          int                 `json:"-"` // GET=0|1 (default); POST>1
-	FullURL       bool                `json:"-"` // FullURL flag; set POST:true, Size=1/+ for full url processing
-	Inbox, Outbox chan Job            // worker communication channels
-
-	pacer *time.Ticker   // pace control signaler
-	jobs  sync.WaitGroup // job state control monitor
+	Host   string   `json:"host,omitempty"`   // host: scheme://host:port
+	Hosts  []string `json:"hosts,omitempty"`  // cluster hosts: scheme://host:port list
+	Secret string   `json:"secret,omitempty"` // worker: passKey secret
+
+	Workers       int                 `json:"workers,omitempty"`  // number of workers
+	Path          string              `json:"path,omitempty"`     // host: endpoint path segment
+	Params        string              `json:"params,omitempty"`   // host: endpoint ?param segment
+	AuthHeader    func(*http.Request) `json:"-"`                  // set the auth header
+	Client        *http.Client        `json:"-"`                  // client; default timeout 10-second
+	Pacer         time.Duration       `json:"pacer,omitempty"`    // pacer time delay; sets the default Limiter rate
+	Limiter       *Limiter            `json:"-"`                  // shared request rate limiter; default from Pacer
+	Size          int                 `json:"size,omitempty"`     // GET=0|1 (default); POST>1
+	FullURL       bool                `json:"full_url,omitempty"` // FullURL flag; set POST:true, Size=1/+ for full url processing
+	Flush         time.Duration       `json:"flush,omitempty"`    // POST max batch latency; default 100ms
+	Retry         *Retry              `json:"retry,omitempty"`    // retry policy; nil for a single attempt
+	Body          int                 `json:"body,omitempty"`     // POST body encoding; BodyText (default), BodyJSON, BodyNDJSON
+	Balancer      Balancer            `json:"-"`                  // cluster node selection; default RoundRobin
+	Health        *Health             `json:"-"`                  // cluster health checking; default with multiple hosts
+	Middleware    []Middleware        `json:"-"`                  // http request chain after AuthHeader
+	Metrics       Metrics             `json:"-"`                  // instrumentation; see Registry
+	Tracer        Tracer              `json:"-"`                  // request tracing; see Recorder
+	Cache         *Cache              `json:"-"`                  // result cache; nil for no caching
+	Coalesce      bool                `json:"coalesce,omitempty"` // share one request among in-flight jobs with the same Request() and Payload
+	Journal       *Journal            `json:"-"`                  // durable submitted/completed job log; see OpenJournal
+	Weights       map[Priority]int    `json:"-"`                  // fair share per Priority lane; default DefaultWeights
+	Input         int                 `json:"input,omitempty"`    // request normalization; default Inputs by worker.Path
+	Stream        bool                `json:"stream,omitempty"`   // POST: stream the request body and emit response items as decoded
+	Compress      *Compression        `json:"-"`                  // request and response compression; nil for none
+	Secrets       *Secrets            `json:"-"`                  // rotating secret source; replaces Secret and AuthHeader
+	Inbox, Outbox chan Job            `json:"-"`                  // worker communication channels
+
+	jobs  sync.WaitGroup  // job state control monitor
+	uuid  atomic.Uint64   // job UUID generator
+	nodes []*Node         // cluster nodes
+	stop  func()          // stops the health prober
+	rt    RoundTrip       // AuthHeader and Middleware chain
+	queue [lanes]chan Job // Priority lanes; Normal is the worker.Inbox
+
+	flightMu sync.Mutex         // guards flights
+	flights  map[string]*flight // in-flight requests by cache key
+	keyMu    sync.Mutex         // guards keys
+	keys     map[Job]string     // cache keys of the jobs in progress
+
+	cancel   func()        // cancels the request context
+	abort    chan struct{} // closed when a Shutdown deadline expires
+	aborted  sync.Once     // single abort close
+	mu       sync.RWMutex  // guards the worker.Inbox close
+	closed   bool          // worker.Inbox closed
+	once     sync.Once     // single worker.Inbox close
+	quit     chan struct{} // closed when the worker stops accepting jobs
+	finished chan struct{} // closed after the worker.Outbox is closed
+	lostMu   sync.Mutex    // guards lost
+	lost     Jobs          // jobs not delivered on the worker.Outbox
 
 }
 
@@ -67,12 +112,22 @@ func (w *Worker) Connect(ctx context.Context) *Worker {
 		w.FullURL = true
 	}
 
+	// batch latency assurance
+	if w.Flush == 0 {
+		w.Flush = time.Millisecond * 100
+	}
+
 	// workers assurance and channel configuration
 	if w.Workers == 0 {
 		w.Workers = 10
 	}
 	w.Inbox = make(chan Job, w.Workers*3/2)
+	w.queue = [lanes]chan Job{Normal: w.Inbox,
+		Interactive: make(chan Job, w.Workers*3/2), Bulk: make(chan Job, w.Workers*3/2)}
 	w.Outbox = make(chan Job, w.Workers*w.Size*3/2)
+	w.quit = make(chan struct{})
+	w.finished = make(chan struct{})
+	w.abort = make(chan struct{})
 
 	// client with default timeout
 	if w.Client == nil {
@@ -80,32 +135,83 @@ func (w *Worker) Connect(ctx context.Context) *Worker {
 	}
 
 	// configure authentication
-	if w.AuthHeader == nil {
+	if w.Secrets != nil {
+		w.Secrets.configure(ctx, w)
+	} else if w.AuthHeader == nil {
 		w.AuthHeader = passkey.NewClient(ctx, w.Secret).SetHeader
 	}
+	w.rt = w.chain()
 
-	// configure pacer
+	// configure pacer and rate limiter
 	if w.Pacer == 0 {
 		w.Pacer = time.Millisecond * 10 // 100 rps
 	}
-	w.pacer = time.NewTicker(w.Pacer)
+	if w.Limiter == nil {
+		w.Limiter = new(Limiter)
+	}
+	w.Limiter.configure(w.Pacer, w.Weights)
+
+	// configure retry policy
+	if w.Retry != nil {
+		w.Retry.configure()
+	}
+
+	// configure request normalization
+	if w.Input == 0 {
+		if w.Input = Inputs[w.Path]; w.Input == 0 {
+			w.Input = InputRaw
+		}
+	}
+
+	// configure compression
+	if w.Compress != nil {
+		w.Compress.configure()
+	}
+
+	// configure result cache
+	if w.Cache != nil {
+		w.Cache.configure(w.Path)
+	}
 
 	// configure host/method and ?param assurance
 	//  GET  .../method/{host}?{param}
 	//  POST .../method?param
-	if len(w.Host) == 0 {
+	if len(w.Host) == 0 && len(w.Hosts) == 0 {
 		w.Host = "http://localhost:1455"
 	}
-	if !strings.HasPrefix(w.Host, "http://") && !strings.HasPrefix(w.Host, "https://") {
-		w.Host = "http://" + w.Host
-	}
-	if len(w.Path) > 0 {
-		w.Host += "/" + strings.TrimPrefix(w.Path, "/")
+	w.nodes = w.nodes[:0]
+	for _, host := range append([]string{w.Host}, w.Hosts...) {
+		if len(host) > 0 {
+			w.nodes = append(w.nodes, newNode(host, w.Path))
+		}
 	}
+	w.Host = w.nodes[0].URL
 	if len(w.Params) > 0 && !strings.HasPrefix(w.Params, "?") {
 		w.Params = "?" + w.Params
 	}
-	w.Host = strings.TrimSuffix(w.Host, "/")
+
+	// configure cluster balancing and health checking
+	if w.Balancer == nil {
+		w.Balancer = new(RoundRobin)
+	}
+	if w.Health == nil && len(w.nodes) > 1 {
+		w.Health = new(Health)
+	}
+	w.stop = func() {}
+	if w.Health != nil {
+		w.Health.configure()
+		var probe context.Context
+		probe, w.stop = context.WithCancel(ctx)
+		go w.probe(probe)
+	}
+
+	// request context; canceled when a Shutdown deadline expires
+	ctx, w.cancel = context.WithCancel(ctx)
+
+	// the scheduler takes the jobs from the Priority lanes in weighted
+	// fair order as the workers or the batcher are ready for them
+	dispatch := make(chan queued)
+	go w.schedule(dispatch)
 
 	if !w.FullURL { // GET
 
@@ -113,8 +219,12 @@ func (w *Worker) Connect(ctx context.Context) *Worker {
 		w.jobs.Add(w.Workers)
 		for range w.Workers {
 			go func() {
-				for job := range w.Inbox {
-					w.get(ctx, job)
+				for q := range dispatch {
+					if ctx.Err() != nil {
+						w.abandon(q.jobs[0])
+						continue
+					}
+					w.get(WithPriority(ctx, q.p), q.jobs[0])
 				}
 				w.jobs.Done()
 			}()
@@ -122,85 +232,463 @@ func (w *Worker) Connect(ctx context.Context) *Worker {
 
 	} else { // POST
 
+		// a single shared batcher fills Size batches from the Inbox and
+		// flushes partial batches after the Flush latency window
+		batches := make(chan queued, w.Workers)
+		go w.batch(dispatch, batches)
+
 		// uses a multi item Jobs object
 		w.jobs.Add(w.Workers)
 		for range w.Workers {
 			go func() {
-				var jobs Jobs
-				for job := range w.Inbox {
-					jobs = append(jobs, job)
-					if len(jobs) == w.Size {
-						w.post(ctx, jobs)
-						jobs.Reset()
-					}
-				}
-				if len(jobs) > 0 {
-					w.post(ctx, jobs)
+				for q := range batches {
+					w.post(WithPriority(ctx, q.p), q.jobs)
 				}
 				w.jobs.Done()
 			}()
 		}
 
 	}
+	w.gauges()
+	go w.finish()
 
 	return w
 }
 
-// Done shuts down the channels and cleanly exitis
-func (w *Worker) Done() {
-	close(w.Inbox)
-	w.jobs.Wait()
-	close(w.Outbox)
-	w.pacer.Stop()
+// queued jobs with their Priority lane
+type queued struct {
+	jobs Jobs
+	p    Priority
 }
 
-// GET .../method/{host}?{param}
-func (w *Worker) get(ctx context.Context, job Job) {
+// schedule moves the jobs from the Priority lanes to dispatch using weighted
+// fair scheduling and closes dispatch once every lane is closed and drained
+func (w *Worker) schedule(dispatch chan<- queued) {
+
+	defer close(dispatch)
+
+	queue := w.queue // closed lanes are set to nil
+	f := newFair(w.Weights)
+	for {
+		var job Job
+		p, ok := f.next(func(p Priority) bool { return len(queue[p]) > 0 })
+		if ok {
+			job = <-queue[p] // the scheduler is the only receiver
+		} else {
+			if queue == [lanes]chan Job{} {
+				return
+			}
+			select {
+			case job, ok = <-queue[Interactive]:
+				p = Interactive
+			case job, ok = <-queue[Normal]:
+				p = Normal
+			case job, ok = <-queue[Bulk]:
+				p = Bulk
+			}
+			if !ok {
+				queue[p] = nil
+				continue
+			}
+		}
+		dispatch <- queued{jobs: Jobs{job}, p: p}
+	}
+}
 
-	w.jobs.Add(1)
+// batch fills POST batches per Priority lane from the dispatched jobs and
+// sends each batch when it reaches the worker.Size or after the worker.Flush
+// latency window from its first job
+func (w *Worker) batch(dispatch <-chan queued, batches chan<- queued) {
 
-	req, _ := http.NewRequest("GET", w.Host+"/"+job.Request()+w.Params, nil)
+	defer close(batches)
 
-	w.AuthHeader(req)
-	resp, err := w.Client.Do(req)
-	if err == nil && resp.StatusCode == 200 {
-		json.NewDecoder(resp.Body).Decode(&job)
-		<-w.pacer.C
+	var pending [lanes]Jobs
+	var timers [lanes]*time.Timer
+	for p := range lanes {
+		timers[p] = time.NewTimer(w.Flush)
+		timers[p].Stop()
+	}
+	send := func(p Priority) {
+		timers[p].Stop()
+		if len(pending[p]) > 0 {
+			batches <- queued{jobs: pending[p], p: p}
+			pending[p] = nil
+		}
 	}
 
-	select {
-	case w.Outbox <- job:
-	case <-ctx.Done():
+	for {
+		select {
+		case q, ok := <-dispatch:
+			if !ok {
+				for _, p := range order {
+					send(p)
+				}
+				return
+			}
+			pending[q.p] = append(pending[q.p], q.jobs...)
+			if len(pending[q.p]) == 1 {
+				timers[q.p].Reset(w.Flush)
+			}
+			if len(pending[q.p]) == w.Size {
+				send(q.p)
+			}
+
+		case <-timers[Interactive].C:
+			send(Interactive)
+		case <-timers[Normal].C:
+			send(Normal)
+		case <-timers[Bulk].C:
+			send(Bulk)
+		}
 	}
+}
+
+// get emits the job on the worker.Outbox after fetch
+func (w *Worker) get(ctx context.Context, job Job) {
+
+	w.jobs.Add(1)
+	w.Journal.record(submitted, job)
+
+	w.fetch(ctx, job)
+	w.emit(ctx, job)
+
 	w.jobs.Done()
 
 }
 
-// POST .../method?{param}
+// post emits the jobs on the worker.Outbox after fetchBatch
 func (w *Worker) post(ctx context.Context, jobs Jobs) {
 
 	w.jobs.Add(len(jobs))
+	w.Journal.record(submitted, jobs...)
+
+	// streamed response jobs are emitted as soon as they are decoded
+	emitted := make(map[Job]bool)
+	var deliver func(Job)
+	if w.Stream {
+		deliver = func(job Job) {
+			emitted[job] = true
+			w.emit(ctx, job)
+			w.jobs.Done()
+		}
+	}
+	if ctx.Err() == nil {
+		w.fetchBatch(ctx, jobs, deliver)
+	}
 
-	var buf bytes.Buffer
 	for i := range jobs {
-		buf.WriteString(jobs[i].Request())
-		buf.WriteByte(10) // \n
+		if !emitted[jobs[i]] {
+			w.emit(ctx, jobs[i])
+			w.jobs.Done()
+		}
 	}
 
-	req, _ := http.NewRequest("POST", w.Host+w.Params, &buf)
-	w.AuthHeader(req)
-	resp, err := w.Client.Do(req)
-	if err == nil && resp.StatusCode == 200 {
-		json.NewDecoder(resp.Body).Decode(&jobs)
-		<-w.pacer.C
+}
+
+// GET .../method/{host}?{param}
+func (w *Worker) fetch(ctx context.Context, job Job) {
+
+	defer w.forget(job)
+	w.track(Jobs{job})
+	if !w.normalize(job) {
+		return
 	}
+	if w.cached(job) {
+		return
+	}
+	if w.Coalesce {
+		leaders, passengers := w.board(Jobs{job})
+		if len(passengers) > 0 && w.await(ctx, "GET", passengers[0]) {
+			return
+		}
+		defer w.land(leaders...)
+	}
+	w.request(ctx, job)
 
-	for i := range jobs {
-		select {
-		case w.Outbox <- jobs[i]:
-		case <-ctx.Done():
+}
+
+// request performs the GET request for job with retries
+func (w *Worker) request(ctx context.Context, job Job) {
+
+	ctx, span := w.trace(ctx, "worker GET")
+	for attempt := 0; ; attempt++ {
+		err := w.do(ctx, "GET", job.Request(), "/"+url.PathEscape(job.Request())+w.Params, nil, func(r io.Reader, _ string) error {
+			return json.NewDecoder(r).Decode(job)
+		})
+		if err == nil {
+			break
+		}
+		fail(job, err)
+		if !w.Retry.wait(ctx, attempt, err) {
+			break
+		}
+		w.count("worker_retries_total", 1, "method", "GET")
+		span.Event("retry", "attempt", strconv.Itoa(attempt+1))
+		job.Fail(0, nil) // reset for the next attempt
+	}
+	w.items(job)
+	w.remember(job)
+	traceJobs(span, "job", job)
+	span.End(job.Err())
+
+}
+
+// POST .../method?{param}; with worker.Stream the completed jobs are passed
+// to deliver as soon as their response item is decoded
+func (w *Worker) fetchBatch(ctx context.Context, jobs Jobs, deliver func(Job)) {
+
+	defer w.forget(jobs...)
+	w.track(jobs)
+	jobs = w.uncached(w.normalized(jobs))
+	if !w.Coalesce {
+		w.requestBatch(ctx, jobs, deliver)
+		return
+	}
+
+	// the passengers of flights led from this batch are released by land
+	// so that batches waiting on each other cannot deadlock
+	leaders, passengers := w.board(jobs)
+	landed := deliver
+	if deliver != nil {
+		landed = func(job Job) {
+			w.land(job)
+			deliver(job)
+		}
+	}
+	w.requestBatch(ctx, leaders, landed)
+	w.land(leaders...)
+	var again Jobs
+	for i := range passengers {
+		if !w.await(ctx, "POST", passengers[i]) {
+			again = append(again, passengers[i].job)
+		}
+	}
+	w.requestBatch(ctx, again, deliver)
+
+}
+
+// requestBatch performs the POST request for jobs with retries; jobs are
+// passed to deliver when the response is streamed, see worker.Stream
+func (w *Worker) requestBatch(ctx context.Context, jobs Jobs, deliver func(Job)) {
+
+	if len(jobs) == 0 {
+		return
+	}
+	ctx, span := w.trace(ctx, "worker POST")
+	span.SetAttr("batch.size", strconv.Itoa(len(jobs)))
+	var failure error // last batch request failure
+
+	// a streamed job is completed and released when its item is decoded
+	// unless it failed and is to be resubmitted by Retry.Items
+	released := make(map[Job]bool)
+	complete := func(jobs ...Job) {
+		w.items(jobs...)
+		w.remember(jobs...)
+		traceJobs(span, "job", jobs...)
+	}
+	accept := func(job Job) bool {
+		if !job.Okay() && w.Retry != nil && w.Retry.Items {
+			return false
+		}
+		released[job] = true
+		complete(job)
+		deliver(job)
+		return true
+	}
+	unreleased := func(jobs Jobs) Jobs {
+		return slices.DeleteFunc(slices.Clone(jobs), func(job Job) bool { return released[job] })
+	}
+
+	// the whole batch is resubmitted on request failures while only the
+	// failed items are resubmitted when Retry.Items is set
+	pending := jobs
+	for attempt := 0; ; attempt++ {
+
+		body, err := w.body(pending)
+		if err != nil {
+			for i := range pending {
+				fail(pending[i], &Error{Method: "POST", URL: w.Host + w.Params, Err: err})
+			}
+			break
+		}
+
+		w.measure("worker_batch_size", float64(len(pending)))
+		rerr := w.do(ctx, "POST", pending[0].Request(), w.Params, body, func(r io.Reader, url string) (err error) {
+			var unexpected int
+			if deliver != nil {
+				unexpected, err = stream(r, pending, "POST", url, accept)
+			} else {
+				unexpected, err = correlate(r, pending, "POST", url)
+			}
+			if unexpected > 0 {
+				w.count("worker_unexpected_items_total", float64(unexpected))
+				span.Event("unexpected", "items", strconv.Itoa(unexpected))
+			}
+			return err
+		})
+		if pending = unreleased(pending); len(pending) == 0 {
+			failure = nil
+			break
+		}
+		if rerr != nil {
+			failure = rerr
+			for i := range pending {
+				fail(pending[i], rerr)
+			}
+			if !w.Retry.wait(ctx, attempt, rerr) {
+				break
+			}
+		} else {
+			failure = nil
+			if w.Retry == nil || !w.Retry.Items {
+				break
+			}
+			var failed Jobs
+			for i := range pending {
+				if !pending[i].Okay() {
+					failed = append(failed, pending[i])
+				}
+			}
+			if len(failed) == 0 || !w.Retry.wait(ctx, attempt, nil) {
+				break
+			}
+			pending = failed
+		}
+
+		w.count("worker_retries_total", 1, "method", "POST")
+		span.Event("retry", "attempt", strconv.Itoa(attempt+1), "items", strconv.Itoa(len(pending)))
+		for i := range pending {
+			pending[i].Fail(0, nil) // reset for the next attempt
+		}
+	}
+	complete(unreleased(jobs)...)
+	span.End(failure)
+
+}
+
+// body returns the POST request body source for jobs; the body is encoded
+// once and replayed on every attempt, or encoded while it is sent when the
+// request is streamed
+func (w *Worker) body(jobs Jobs) (func() io.Reader, error) {
+
+	if w.Stream {
+		return func() io.Reader {
+			r, pw := io.Pipe()
+			go func() { pw.CloseWithError(encode(pw, w.Body, jobs)) }()
+			return r
+		}, nil
+	}
+
+	var buf bytes.Buffer
+	if err := encode(&buf, w.Body, jobs); err != nil {
+		return nil, err
+	}
+	return func() io.Reader { return bytes.NewReader(buf.Bytes()) }, nil
+}
+
+// do performs the request on a cluster node selected for the request key and
+// fails over to the other healthy nodes on transport, 429, and 5xx failures;
+// a request canceled by the caller, including while it waits on the pacer,
+// is not counted against the node health
+func (w *Worker) do(ctx context.Context, method, key, suffix string, body func() io.Reader, decode func(io.Reader, string) error) *Error {
+
+	if w.Compress != nil && w.Compress.err != nil {
+		return &Error{Method: method, URL: w.Host + suffix, Err: w.Compress.err}
+	}
+
+	var tried []*Node
+	for {
+		node := w.pick(key, tried)
+		node.outstanding.Add(1)
+		err := w.send(ctx, method, node.URL+suffix, body, decode)
+		node.outstanding.Add(-1)
+		if ctx.Err() == nil {
+			w.observe(node, err)
+		}
+
+		if err == nil || !err.failover() || ctx.Err() != nil {
+			return err
+		}
+		if tried = append(tried, node); w.pick(key, tried) == nil {
+			return err
 		}
-		w.jobs.Done()
+	}
+}
+
+// send performs the request and decodes a http.StatusOK response body;
+// every transport, status, and decode failure is reported as an *Error
+func (w *Worker) send(ctx context.Context, method, url string, body func() io.Reader, decode func(io.Reader, string) error) (failure *Error) {
+
+	ctx, span := w.trace(ctx, "worker http "+method)
+	span.SetAttr("http.url", url)
+	defer func() {
+		if failure != nil {
+			span.End(failure)
+			return
+		}
+		span.End(nil)
+	}()
+
+	wait := time.Now()
+	if err := w.Limiter.Wait(ctx); err != nil {
+		return &Error{Method: method, URL: url, Err: err}
+	}
+	w.measure("worker_pacer_wait_seconds", time.Since(wait).Seconds())
+	span.Event("pacer", "wait_seconds", strconv.FormatFloat(time.Since(wait).Seconds(), 'f', -1, 64))
+
+	var rd io.Reader
+	var coding string
+	if body != nil {
+		rd, coding = w.compress(body())
+		if c, ok := rd.(io.Closer); ok {
+			defer c.Close() // stops a streamed body encoder
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, rd)
+	if err != nil {
+		return &Error{Method: method, URL: url, Err: err}
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", contentType(w.Body))
+	}
+	if len(coding) > 0 {
+		req.Header.Set("Content-Encoding", coding)
+	}
+	if w.Compress != nil {
+		req.Header.Set("Accept-Encoding", w.Compress.accept)
+	}
+	if tp := span.TraceParent(); len(tp) > 0 {
+		req.Header.Set("traceparent", tp)
+	}
+	start := time.Now()
+	resp, err := w.rt(req)
+	latency := time.Since(start)
+	w.measure("worker_request_seconds", latency.Seconds(), "method", method)
+	if err != nil {
+		w.count("worker_requests_total", 1, "method", method, "status", "0")
+		if ctx.Err() == nil {
+			w.Limiter.observe(0, latency)
+		}
+		return &Error{Method: method, URL: url, Err: err}
+	}
+	defer resp.Body.Close()
+	w.count("worker_requests_total", 1, "method", method, "status", strconv.Itoa(resp.StatusCode))
+	span.SetAttr("http.status_code", strconv.Itoa(resp.StatusCode))
+	w.Limiter.observe(resp.StatusCode, latency)
+
+	if resp.StatusCode != http.StatusOK {
+		return &Error{Method: method, URL: url, StatusCode: resp.StatusCode,
+			RetryAfter: retryAfter(resp.Header)}
+	}
+	r, done, err := w.decompress(resp)
+	if err != nil {
+		return &Error{Method: method, URL: url, StatusCode: resp.StatusCode, Err: err}
+	}
+	defer done()
+	if err = decode(r, url); err != nil {
+		return &Error{Method: method, URL: url, StatusCode: resp.StatusCode, Err: err}
 	}
 
+	return nil
 }
@@ -1,9 +1,26 @@
 package client_test
 
 import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/klauspost/compress/zstd"
 	"github.com/zxdev/client/worker/client"
+	"github.com/zxdev/client/worker/fake"
 	"github.com/zxdev/client/worker/job"
 	"github.com/zxdev/passkey"
 )
@@ -14,10 +31,39 @@ var (
 	// HOST and SECRET value; these are normally loaded
 	// via env.Conf(&work,path) from the resouce file
 
-	host   = "http://wrk.netstar.dev:1455"      // requires localhost server instance runnning
+	host   = os.Getenv("WORKER_HOST")           // live cluster; default is the in-process fake node
 	secret = "NETSTARXNETSTARXXNETSTARXNETSTAR" // required but is ignored by localhost server
 )
 
+// TestMain serves the endpoint tests from a fake.Server unless WORKER_HOST
+// names a live worker cluster
+func TestMain(m *testing.M) {
+
+	if len(host) == 0 {
+		ctx, cancel := context.WithCancel(context.Background())
+		host = (&fake.Server{Secret: secret, Respond: respond}).Start(ctx).URL
+		code := m.Run()
+		cancel()
+		os.Exit(code)
+	}
+	os.Exit(m.Run())
+}
+
+// respond fills the fake.Server items the endpoint tests verify; other
+// items echo the request key
+func respond(path, key string) any {
+
+	if path == "cert" {
+		return job.Cert{
+			Connection:   &job.ConnectionInfo{TLSVersion: "TLS 1.3", CipherSuite: "TLS_AES_128_GCM_SHA256"},
+			Verification: &job.VerificationInfo{HostnameMatches: true, ChainVerified: true, ChainLength: 2},
+			Certs:        []job.CertificateInfo{{SubjectCN: key, IssuerCN: "Fake CA"}, {SubjectCN: "Fake CA", IssuerCN: "Fake CA"}},
+		}
+	}
+
+	return nil
+}
+
 /*
 
 	// PRODUCTION EXAMPLES
@@ -379,3 +425,1579 @@ func TestCERT(t *testing.T) {
 		}
 	}
 }
+
+// go test -v client/client_test.go --run=FAIL
+func TestFAIL(t *testing.T) {
+
+	// a cluster that is down or overloaded must never produce
+	// jobs that report Okay() with empty results
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	items := []string{"one.com", "two.com", "three.com"}
+
+	for size := range 2 {
+		var work = client.Worker{
+			Host:       srv.URL,
+			AuthHeader: func(*http.Request) {},
+			Size:       size + 1,
+			Path:       "dns",
+		}
+		work.Connect(t.Context())
+
+		go func() {
+			defer work.Done()
+			for i := range items {
+				work.Inbox <- job.NewDNS(items[i])
+			}
+		}()
+
+		var n int
+		for j := range work.Outbox {
+			n++
+			var e *client.Error
+			if j.Okay() || !errors.As(j.Err(), &e) {
+				t.Fatal("expected failure", j.Request(), j.Err())
+			}
+			if r := j.Unpack().(job.DNS); r.Status != http.StatusServiceUnavailable {
+				t.Fatal("expected status 503", r.Status)
+			}
+		}
+		if n != len(items) {
+			t.Fatal("expected", len(items), "jobs; got", n)
+		}
+	}
+
+	// transport failure; nothing listening
+	var work = client.Worker{
+		Host:       "http://127.0.0.1:1",
+		AuthHeader: func(*http.Request) {},
+		Path:       "title",
+	}
+	work.Connect(t.Context())
+	go func() {
+		defer work.Done()
+		work.Inbox <- job.NewTitle("zxdev.com")
+	}()
+	for j := range work.Outbox {
+		if j.Okay() || j.Err() == nil {
+			t.Fatal("expected transport failure", j.Request())
+		}
+		if r := j.Unpack().(job.Title); r.Status != client.StatusFailed {
+			t.Fatal("expected StatusFailed", r.Status)
+		}
+	}
+}
+
+// go test -v client/client_test.go --run=RETRY
+func TestRETRY(t *testing.T) {
+
+	// the first two requests fail with 503 and the remainder succeed
+	// while POST reports a per item failure on the first batch only
+
+	var n atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch c := n.Add(1); {
+		case c <= 2:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case r.Method == "GET":
+			w.Write([]byte(`{"url":"zxdev.com","title":"Zx Development"}`))
+		case c == 3:
+			w.Write([]byte(`[{"url":"one.com","status":500},{"url":"two.com","title":"Two"}]`))
+		default:
+			w.Write([]byte(`[{"url":"one.com","title":"One"}]`))
+		}
+	}))
+	defer srv.Close()
+
+	retry := client.Retry{Attempts: 4, Base: time.Millisecond, Items: true}
+
+	n.Store(0)
+	var work = client.Worker{
+		Host:       srv.URL,
+		AuthHeader: func(*http.Request) {},
+		Path:       "title",
+		Retry:      &retry,
+	}
+	work.Connect(t.Context())
+	go func() {
+		defer work.Done()
+		work.Inbox <- job.NewTitle("zxdev.com")
+	}()
+	for j := range work.Outbox {
+		if !j.Okay() || j.Unpack().(job.Title).Title != "Zx Development" {
+			t.Fatal("expected success after retry", j.Err())
+		}
+	}
+
+	n.Store(0)
+	var bulk = client.Worker{
+		Host:       srv.URL,
+		AuthHeader: func(*http.Request) {},
+		Path:       "title",
+		Size:       2,
+		Workers:    1,
+		Retry:      &retry,
+	}
+	bulk.Connect(t.Context())
+	go func() {
+		defer bulk.Done()
+		bulk.Inbox <- job.NewTitle("one.com")
+		bulk.Inbox <- job.NewTitle("two.com")
+	}()
+	for j := range bulk.Outbox {
+		if !j.Okay() || len(j.Unpack().(job.Title).Title) == 0 {
+			t.Fatal("expected success after retry", j.Request(), j.Err())
+		}
+	}
+	if n.Load() != 4 {
+		t.Fatal("expected 4 requests; got", n.Load())
+	}
+}
+
+// go test -v client/client_test.go --run=BODY
+func TestBODY(t *testing.T) {
+
+	// ndjson request items carry the per job CertJob options
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/x-ndjson" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var resp []job.Cert
+		scanner := bufio.NewScanner(r.Body)
+		for scanner.Scan() {
+			var req job.CertJob
+			if json.Unmarshal(scanner.Bytes(), &req) != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			c := job.Cert{Host: req.Host, Port: req.Port}
+			if req.Options != nil && req.Options.IncludeOCSP {
+				c.Verification = &job.VerificationInfo{OCSPChecked: true}
+			}
+			resp = append(resp, c)
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer srv.Close()
+
+	var work = client.Worker{
+		Host:       srv.URL,
+		AuthHeader: func(*http.Request) {},
+		Path:       "cert",
+		Size:       2,
+		Workers:    1,
+		Body:       client.BodyNDJSON,
+	}
+	work.Connect(t.Context())
+	go func() {
+		defer work.Done()
+		work.Inbox <- &job.Cert{Host: "one.com", Port: 8443, Options: &job.CertOptions{IncludeOCSP: true}}
+		work.Inbox <- job.NewCert("two.com")
+	}()
+	for j := range work.Outbox {
+		if !j.Okay() {
+			t.Fatal(j.Request(), j.Err())
+		}
+		r := j.Unpack().(job.Cert)
+		if ocsp := r.Verification != nil && r.Verification.OCSPChecked; ocsp != (r.Host == "one.com") {
+			t.Fatal("options not delivered", r.Host, r.Port)
+		}
+	}
+}
+
+// go test -v client/client_test.go --run=UUID
+func TestUUID(t *testing.T) {
+
+	// the server answers in reverse order, drops the last item, and adds an
+	// unknown item; results must land on the originating jobs by UUID
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req []job.Title
+		if json.NewDecoder(r.Body).Decode(&req) != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		resp := []job.Title{{UUID: 1 << 60, Url: "unknown.com", Title: "?"}}
+		for i := len(req) - 2; i >= 0; i-- {
+			resp = append(resp, job.Title{UUID: req[i].UUID, Url: req[i].Url, Title: "title " + req[i].Url})
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer srv.Close()
+
+	items := []string{"one.com", "two.com", "three.com", "four.com"}
+
+	var work = client.Worker{
+		Host:       srv.URL,
+		AuthHeader: func(*http.Request) {},
+		Path:       "title",
+		Size:       len(items),
+		Workers:    1,
+		Body:       client.BodyJSON,
+	}
+	work.Connect(t.Context())
+	go func() {
+		defer work.Done()
+		for i := range items {
+			work.Inbox <- job.NewTitle(items[i])
+		}
+	}()
+
+	for j := range work.Outbox {
+		r := j.Unpack().(job.Title)
+		if r.UUID == 0 {
+			t.Fatal("uuid not assigned", r.Url)
+		}
+		switch r.Url {
+		case "four.com":
+			if j.Okay() || !errors.Is(j.Err(), client.ErrMissing) || !errors.Is(j.Err(), client.ErrUnexpected) {
+				t.Fatal("expected missing item", r.Url, j.Err())
+			}
+		default:
+			if !j.Okay() || r.Title != "title "+r.Url {
+				t.Fatal("misattributed result", r.Url, r.Title, j.Err())
+			}
+		}
+	}
+}
+
+// go test -v client/client_test.go --run=EXTRA
+func TestEXTRA(t *testing.T) {
+
+	// the server answers every job and adds an unknown item without echoing
+	// the UUIDs; the jobs succeed and the extra item is still reported
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"url":"a.com","title":"a"},{"url":"b.com","title":"b"},{"url":"zzz.com","title":"?"}]`))
+	}))
+	defer srv.Close()
+
+	var registry client.Registry
+	var work = client.Worker{
+		Host:       srv.URL,
+		AuthHeader: func(*http.Request) {},
+		Path:       "title",
+		Size:       2,
+		Workers:    1,
+		Body:       client.BodyJSON,
+		Metrics:    &registry,
+	}
+	work.Connect(t.Context())
+	go func() {
+		defer work.Done()
+		work.Inbox <- job.NewTitle("a.com")
+		work.Inbox <- job.NewTitle("b.com")
+	}()
+
+	for j := range work.Outbox {
+		if !j.Okay() {
+			t.Fatal("expected ok", j.Request(), j.Err())
+		}
+	}
+	if n := registry.Value("worker_unexpected_items_total", map[string]string{"path": "title"}); n != 1 {
+		t.Fatal("expected 1 unexpected item", n)
+	}
+}
+
+// go test -v client/client_test.go --run=LIMIT
+func TestLIMIT(t *testing.T) {
+
+	// the hard ceiling paces every request including failures while
+	// AIMD backs the rate off on 503 responses
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	limiter := client.Limiter{Max: 50, AIMD: true}
+	var work = client.Worker{
+		Host:       srv.URL,
+		AuthHeader: func(*http.Request) {},
+		Path:       "dns",
+		Limiter:    &limiter,
+	}
+	work.Connect(t.Context())
+
+	start := time.Now()
+	go func() {
+		defer work.Done()
+		for range 10 {
+			work.Inbox <- job.NewDNS("zxdev.com")
+		}
+	}()
+	for range work.Outbox {
+	}
+
+	if d := time.Since(start); d < time.Millisecond*150 {
+		t.Fatal("requests were not paced", d)
+	}
+	if rate := limiter.Current(); rate >= 50 {
+		t.Fatal("expected AIMD backoff", rate)
+	}
+}
+
+// go test -v client/client_test.go --run=CLUSTER
+func TestCLUSTER(t *testing.T) {
+
+	// requests routed to the failing node are re-routed to the healthy
+	// node and the failing node is ejected from rotation
+
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer down.Close()
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"host":"zxdev.com","nameserver":["aron.ns.cloudflare.com"]}`))
+	}))
+	defer up.Close()
+
+	var work = client.Worker{
+		Hosts:      []string{down.URL, up.URL},
+		AuthHeader: func(*http.Request) {},
+		Path:       "rdap",
+		Health:     &client.Health{Failures: 2, Interval: time.Hour},
+	}
+	work.Connect(t.Context())
+	go func() {
+		defer work.Done()
+		for range 10 {
+			work.Inbox <- job.NewRdap("zxdev.com")
+		}
+	}()
+	for j := range work.Outbox {
+		if !j.Okay() {
+			t.Fatal("expected failover", j.Err())
+		}
+	}
+
+	if nodes := work.Nodes(); nodes[0].Healthy() || !nodes[1].Healthy() {
+		t.Fatal("expected failing node ejection")
+	}
+}
+
+// go test -v client/client_test.go --run=DEADLINE
+func TestDEADLINE(t *testing.T) {
+
+	// requests abandoned by the caller deadline leave the healthy but slow
+	// nodes in rotation
+
+	slow := func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Millisecond * 50):
+		case <-r.Context().Done():
+		}
+		w.Write([]byte(`{"host":"zxdev.com"}`))
+	}
+	one := httptest.NewServer(http.HandlerFunc(slow))
+	defer one.Close()
+	two := httptest.NewServer(http.HandlerFunc(slow))
+	defer two.Close()
+
+	var work = client.Worker{
+		Hosts:      []string{one.URL, two.URL},
+		AuthHeader: func(*http.Request) {},
+		Path:       "rdap",
+		Health:     &client.Health{Failures: 2, Interval: time.Hour},
+	}
+	work.Connect(t.Context())
+	defer work.Done()
+
+	for range 6 {
+		ctx, cancel := context.WithTimeout(t.Context(), time.Millisecond*5)
+		if _, err := work.Do(ctx, job.NewRdap("zxdev.com")); err == nil {
+			t.Fatal("expected deadline failure")
+		}
+		cancel()
+	}
+	for _, node := range work.Nodes() {
+		if !node.Healthy() {
+			t.Fatal("expected node in rotation", node.URL)
+		}
+	}
+}
+
+// go test -v client/client_test.go --run=DO
+func TestDO(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			if r.URL.Path == "/method/bad.com" {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			w.Write([]byte(`{"url":"zxdev.com","flag":3}`))
+		default:
+			var req []job.Method
+			json.NewDecoder(r.Body).Decode(&req)
+			for i := range req {
+				req[i].Flag = 7
+			}
+			json.NewEncoder(w).Encode(req)
+		}
+	}))
+	defer srv.Close()
+
+	var work = client.Worker{
+		Host:       srv.URL,
+		AuthHeader: func(*http.Request) {},
+		Path:       "method",
+	}
+	work.Connect(t.Context())
+	defer work.Done()
+
+	j, err := work.Do(t.Context(), job.NewMethod("zxdev.com"))
+	if err != nil || j.Unpack().(job.Method).Flag != 3 {
+		t.Fatal("expected flag 3", err)
+	}
+	if _, err = work.Do(t.Context(), job.NewMethod("bad.com")); err == nil {
+		t.Fatal("expected failure")
+	}
+
+	var bulk = client.Worker{
+		Host:       srv.URL,
+		AuthHeader: func(*http.Request) {},
+		Path:       "method",
+		Size:       2,
+		Body:       client.BodyJSON,
+	}
+	bulk.Connect(t.Context())
+	defer bulk.Done()
+
+	items := []string{"one.com", "two.com", "three.com", "four.com", "Five.COM."} // keyed as given
+	var jobs client.Jobs
+	for i := range items {
+		jobs = append(jobs, job.NewMethod(items[i]))
+	}
+	result, err := bulk.DoBatch(t.Context(), jobs)
+	if err != nil || len(result) != len(items) {
+		t.Fatal("expected", len(items), "results", err)
+	}
+	for _, item := range items {
+		if r := result[item].Unpack().(job.Method); r.Flag != 7 {
+			t.Fatal("expected flag 7", item, r.Flag)
+		}
+	}
+}
+
+// go test -v client/client_test.go --run=TYPED
+func TestTYPED(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"rcode":1,"host":"zxdev.com","a":["185.199.108.153"]}`))
+	}))
+	defer srv.Close()
+
+	var work = client.Typed[*job.DNS]{Worker: client.Worker{
+		Host:       srv.URL,
+		AuthHeader: func(*http.Request) {},
+		Path:       "dns",
+	}}
+	work.Connect(t.Context())
+
+	go func() {
+		defer work.Done()
+		for range 5 {
+			work.Inbox <- job.NewDNS("zxdev.com")
+		}
+	}()
+
+	var n int
+	for r := range work.Outbox { // *job.DNS; no Unpack type assertion
+		if !r.Okay() || len(r.A) != 1 {
+			t.Fatal("expected A record", r.Err())
+		}
+		n++
+	}
+	if n != 5 {
+		t.Fatal("expected 5 results; got", n)
+	}
+
+	var batch = client.Typed[*job.DNS]{Worker: client.Worker{
+		Host:       srv.URL,
+		AuthHeader: func(*http.Request) {},
+		Path:       "dns",
+	}}
+	batch.Connect(t.Context())
+	defer batch.Done()
+	result, err := batch.DoBatch(t.Context(), []*job.DNS{job.NewDNS("ZXDEV.com."), job.NewDNS("github.com")})
+	if r, ok := result["ZXDEV.com."]; err != nil || len(result) != 2 || !ok || len(r.A) != 1 {
+		t.Fatal("expected results keyed as given", result, err)
+	}
+}
+
+// go test -v client/client_test.go --run=FLUSH
+func TestFLUSH(t *testing.T) {
+
+	// a partial batch is flushed after the latency window while the
+	// Inbox is still open
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req []job.Title
+		json.NewDecoder(r.Body).Decode(&req)
+		json.NewEncoder(w).Encode(req)
+	}))
+	defer srv.Close()
+
+	var work = client.Worker{
+		Host:       srv.URL,
+		AuthHeader: func(*http.Request) {},
+		Path:       "title",
+		Size:       10,
+		Flush:      time.Millisecond * 20,
+		Body:       client.BodyJSON,
+	}
+	work.Connect(t.Context())
+
+	for _, item := range []string{"one.com", "two.com", "three.com"} {
+		work.Inbox <- job.NewTitle(item)
+	}
+	for range 3 {
+		select {
+		case j := <-work.Outbox:
+			if !j.Okay() {
+				t.Fatal(j.Err())
+			}
+		case <-time.After(time.Second):
+			t.Fatal("partial batch was not flushed")
+		}
+	}
+	work.Done()
+}
+
+// go test -v client/client_test.go --run=SHUTDOWN
+func TestSHUTDOWN(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Millisecond * 100):
+		case <-r.Context().Done():
+		}
+		w.Write([]byte(`{"url":"zxdev.com","title":"Zx Development"}`))
+	}))
+	defer srv.Close()
+
+	var work = client.Worker{
+		Host:       srv.URL,
+		AuthHeader: func(*http.Request) {},
+		Path:       "title",
+		Workers:    4,
+	}
+	work.Connect(t.Context())
+
+	var delivered atomic.Int32
+	go func() {
+		for range work.Outbox {
+			delivered.Add(1)
+		}
+	}()
+
+	for range 5 {
+		if err := work.Submit(t.Context(), job.NewTitle("zxdev.com")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), time.Millisecond*150)
+	defer cancel()
+	lost, err := work.Shutdown(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatal("expected deadline", err)
+	}
+	if len(lost) == 0 || int(delivered.Load())+len(lost) != 5 {
+		t.Fatal("expected every job delivered or returned", delivered.Load(), len(lost))
+	}
+	for i := range lost {
+		if !lost[i].Okay() {
+			t.Fatal("expected canceled jobs to be reset", lost[i].Err())
+		}
+	}
+
+	// idempotent; no panics
+	work.Done()
+	if again, _ := work.Shutdown(t.Context()); len(again) != len(lost) {
+		t.Fatal("expected the same unprocessed jobs")
+	}
+	if err := work.Submit(t.Context(), job.NewTitle("zxdev.com")); !errors.Is(err, client.ErrClosed) {
+		t.Fatal("expected ErrClosed", err)
+	}
+}
+
+// go test -v client/client_test.go --run=MIDDLEWARE
+func TestMIDDLEWARE(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("token") != "auth" || r.Header.Get("traceparent") == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"host":"zxdev.com","mx":["mx01.mail.icloud.com"]}`))
+	}))
+	defer srv.Close()
+
+	// fault injection; the first request never reaches the server
+	var injected atomic.Bool
+	fault := func(next client.RoundTrip) client.RoundTrip {
+		return func(req *http.Request) (*http.Response, error) {
+			if injected.CompareAndSwap(false, true) {
+				return &http.Response{StatusCode: http.StatusServiceUnavailable, Body: http.NoBody, Header: http.Header{}}, nil
+			}
+			return next(req)
+		}
+	}
+
+	var observed atomic.Int32
+	var work = client.Worker{
+		Host:       srv.URL,
+		AuthHeader: func(r *http.Request) { r.Header.Set("token", "auth") },
+		Path:       "mail",
+		Retry:      &client.Retry{Base: time.Millisecond},
+		Middleware: []client.Middleware{
+			client.Mutate(func(r *http.Request) {
+				r.Header.Set("traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+			}),
+			client.Observe(func(*http.Request, *http.Response, error, time.Duration) { observed.Add(1) }),
+			fault,
+		},
+	}
+	work.Connect(t.Context())
+	defer work.Done()
+
+	j, err := work.Do(t.Context(), job.NewMail("zxdev.com"))
+	if err != nil || len(j.Unpack().(job.Mail).MX) != 1 {
+		t.Fatal("expected mx record", err)
+	}
+	if observed.Load() != 2 {
+		t.Fatal("expected 2 observed requests; got", observed.Load())
+	}
+}
+
+// go test -v client/client_test.go --run=METRICS
+func TestMETRICS(t *testing.T) {
+
+	var n atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if n.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`[{"host":"one.com"},{"host":"two.com","status":404}]`))
+	}))
+	defer srv.Close()
+
+	var registry client.Registry
+	var work = client.Worker{
+		Host:       srv.URL,
+		AuthHeader: func(*http.Request) {},
+		Path:       "firewall",
+		Size:       2,
+		Workers:    1,
+		Retry:      &client.Retry{Base: time.Millisecond},
+		Metrics:    &registry,
+	}
+	work.Connect(t.Context())
+	go func() {
+		defer work.Done()
+		work.Inbox <- job.NewFirewall("one.com")
+		work.Inbox <- job.NewFirewall("two.com")
+	}()
+	for range work.Outbox {
+	}
+
+	for name, want := range map[string]float64{
+		`worker_requests_total{method="POST",path="firewall",status="503"}`: 1,
+		`worker_requests_total{method="POST",path="firewall",status="200"}`: 1,
+		`worker_retries_total{method="POST",path="firewall"}`:               1,
+		`worker_items_total{path="firewall",result="ok"}`:                   1,
+		`worker_items_total{path="firewall",result="fail"}`:                 1,
+		`worker_batch_size_sum{path="firewall"}`:                            4,
+		`worker_inbox_depth{path="firewall"}`:                               0,
+	} {
+		var text strings.Builder
+		registry.WritePrometheus(&text)
+		if !strings.Contains(text.String(), name+" "+strconv.FormatFloat(want, 'g', -1, 64)+"\n") {
+			t.Fatal("expected", name, want, "\n"+text.String())
+		}
+	}
+}
+
+// go test -v client/client_test.go --run=TRACE
+func TestTRACE(t *testing.T) {
+
+	var parents = make(chan string, 2)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			w.Write([]byte(`{"host":"three.com"}`))
+			return
+		}
+		parents <- r.Header.Get("traceparent")
+		w.Write([]byte(`[{"host":"one.com"},{"host":"two.com","status":404}]`))
+	}))
+	defer srv.Close()
+
+	var recorder client.Recorder
+	var work = client.Worker{
+		Host:       srv.URL,
+		AuthHeader: func(*http.Request) {},
+		Path:       "firewall",
+		Size:       2,
+		Workers:    1,
+		Tracer:     &recorder,
+	}
+	work.Connect(t.Context())
+	go func() {
+		defer work.Done()
+		work.Inbox <- job.NewFirewall("one.com")
+		work.Inbox <- job.NewFirewall("two.com")
+	}()
+	for range work.Outbox {
+	}
+
+	spans := recorder.Spans()
+	if len(spans) != 2 {
+		t.Fatal("expected http and batch spans", spans)
+	}
+	call, batch := spans[0], spans[1]
+	if batch.Name != "worker POST" || call.Name != "worker http POST" ||
+		call.TraceID != batch.TraceID || call.ParentID != batch.SpanID {
+		t.Fatal("span hierarchy", spans)
+	}
+	if want := "00-" + call.TraceID + "-" + call.SpanID + "-01"; <-parents != want {
+		t.Fatal("traceparent", want)
+	}
+	if len(batch.Events) != 2 || batch.Events[0].Attrs["job.uuid"] == "" ||
+		batch.Events[1].Attrs["job.request"] != "two.com" || batch.Events[1].Attrs["job.okay"] != "false" {
+		t.Fatal("job events", batch.Events)
+	}
+	if call.Attrs["http.status_code"] != "200" || call.Events[0].Name != "pacer" {
+		t.Fatal("http span", call)
+	}
+
+	// GET jobs are tracked by UUID as well
+	var get client.Recorder
+	var single = client.Worker{
+		Host:       srv.URL,
+		AuthHeader: func(*http.Request) {},
+		Path:       "firewall",
+		Tracer:     &get,
+	}
+	single.Connect(t.Context())
+	defer single.Done()
+	single.Do(t.Context(), job.NewFirewall("three.com"))
+	if spans = get.Spans(); len(spans) != 2 || spans[1].Name != "worker GET" || len(spans[1].Events) != 1 ||
+		spans[1].Events[0].Attrs["job.uuid"] == "0" || spans[1].Events[0].Attrs["job.uuid"] == "" {
+		t.Fatal("expected GET job uuid", spans)
+	}
+}
+
+// go test -v client/client_test.go --run=OTLP
+func TestOTLP(t *testing.T) {
+
+	// full batches are sent in the background while the collector is
+	// blocked and Flush waits for them and sends the remaining spans
+
+	release := make(chan struct{})
+	var spans atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		var req struct {
+			ResourceSpans []struct {
+				ScopeSpans []struct {
+					Spans []json.RawMessage `json:"spans"`
+				} `json:"scopeSpans"`
+			} `json:"resourceSpans"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		spans.Add(int64(len(req.ResourceSpans[0].ScopeSpans[0].Spans)))
+	}))
+	defer srv.Close()
+
+	otlp := &client.OTLP{Endpoint: srv.URL, Batch: 2}
+	recorder := client.Recorder{Export: otlp.Export}
+
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, span := recorder.Start(t.Context(), "span")
+			span.End(nil)
+		}()
+	}
+	wg.Wait() // Export does not block on the collector
+	close(release)
+
+	if err := otlp.Flush(t.Context()); err != nil || spans.Load() != 5 {
+		t.Fatal("expected 5 spans", spans.Load(), err)
+	}
+}
+
+// go test -v client/client_test.go --run=CACHE
+func TestCACHE(t *testing.T) {
+
+	var n, version atomic.Int64
+	version.Store(1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n.Add(1)
+		switch r.URL.Path {
+		case "/firewall/bad.com":
+			w.Write([]byte(`{"host":"bad.com","status":404}`))
+		case "/firewall/gone.com":
+			w.WriteHeader(http.StatusBadGateway)
+		default:
+			host := strings.TrimPrefix(r.URL.Path, "/firewall/")
+			w.Write([]byte(`{"host":"` + host + `","version":` + strconv.FormatInt(version.Load(), 10) + `}`))
+		}
+	}))
+	defer srv.Close()
+
+	for _, store := range []client.Store{new(client.LRU), &client.Disk{Dir: t.TempDir()}} {
+
+		n.Store(0)
+		version.Store(1)
+		var work = client.Worker{
+			Host:       srv.URL,
+			AuthHeader: func(*http.Request) {},
+			Path:       "firewall",
+			Cache:      &client.Cache{Store: store},
+		}
+		work.Connect(t.Context())
+
+		for _, host := range []string{"one.com", "one.com", "bad.com", "bad.com", "gone.com", "gone.com"} {
+			work.Do(t.Context(), job.NewFirewall(host))
+		}
+		if n.Load() != 4 {
+			t.Fatal("expected cached ok and negative results, requests", n.Load())
+		}
+		if r, _ := work.Do(t.Context(), job.NewFirewall("bad.com")); r.Okay() || r.Unpack().(job.Firewall).Status != 404 {
+			t.Fatal("expected cached failure", r)
+		}
+
+		// a newer firewall version invalidates the older results
+		version.Store(2)
+		work.Do(t.Context(), job.NewFirewall("two.com"))
+		if r, _ := work.Do(t.Context(), job.NewFirewall("one.com")); n.Load() != 6 || r.Unpack().(job.Firewall).Version != 2 {
+			t.Fatal("expected version refresh, requests", n.Load())
+		}
+		work.Done()
+	}
+}
+
+// go test -v client/client_test.go --run=DISK
+func TestDISK(t *testing.T) {
+
+	// the sweep removes the expired files and the files soonest to expire
+	// above Max without the keys being read again
+
+	dir := t.TempDir()
+	disk := &client.Disk{Dir: dir, Sweep: time.Nanosecond, Max: 2}
+	now := time.Now()
+	disk.Set("expired", []byte("0"), now.Add(-time.Second))
+	disk.Set("one", []byte("1"), now.Add(time.Hour))
+	disk.Set("two", []byte("2"), now.Add(time.Hour*2))
+
+	files := func() (n int) {
+		filepath.WalkDir(dir, func(_ string, e os.DirEntry, err error) error {
+			if err == nil && !e.IsDir() {
+				n++
+			}
+			return nil
+		})
+		return
+	}
+	deadline := time.Now().Add(time.Second * 5)
+	for {
+		disk.Set("three", []byte("3"), now.Add(time.Hour*3)) // starts a sweep
+		if files() == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the sweep to keep 2 files", files())
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+	if _, ok := disk.Get("one"); ok {
+		t.Fatal("expected the file soonest to expire removed")
+	}
+	if v, ok := disk.Get("three"); !ok || string(v) != "3" {
+		t.Fatal("expected the latest file kept")
+	}
+}
+
+// go test -v client/client_test.go --run=CACHEPAYLOAD
+func TestCACHEPAYLOAD(t *testing.T) {
+
+	// the Payload request options are part of the cache key while the port
+	// echoed by the server does not change the key the result is stored under
+
+	var n atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n.Add(1)
+		var req []job.CertJob
+		json.NewDecoder(r.Body).Decode(&req)
+		resp := make([]job.Cert, len(req))
+		for i := range req {
+			resp[i] = job.Cert{UUID: req[i].UUID, Host: req[i].Host, Port: 443}
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer srv.Close()
+
+	var work = client.Worker{
+		Host:       srv.URL,
+		AuthHeader: func(*http.Request) {},
+		Path:       "cert",
+		FullURL:    true,
+		Body:       client.BodyJSON,
+		Cache:      &client.Cache{Store: new(client.LRU)},
+	}
+	work.Connect(t.Context())
+	defer work.Done()
+
+	ocsp := func(host string) *job.Cert {
+		j := job.NewCert(host)
+		j.Options = &job.CertOptions{IncludeOCSP: true}
+		return j
+	}
+	for _, j := range []*job.Cert{job.NewCert("one.com"), job.NewCert("one.com"), ocsp("one.com"), ocsp("one.com")} {
+		if r, err := work.Do(t.Context(), j); err != nil || r.Unpack().(job.Cert).Port != 443 {
+			t.Fatal("expected result", r, err)
+		}
+	}
+	if n.Load() != 2 {
+		t.Fatal("expected a cached result per request options, requests", n.Load())
+	}
+}
+
+// go test -v client/client_test.go --run=COALESCE
+func TestCOALESCE(t *testing.T) {
+
+	var n atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n.Add(1)
+		time.Sleep(time.Millisecond * 100) // keep the request in flight
+		if r.Method == "POST" {
+			var req []job.DNS
+			json.NewDecoder(r.Body).Decode(&req)
+			for i := range req {
+				req[i].A = []string{"10.0.0.1"}
+			}
+			json.NewEncoder(w).Encode(req)
+			return
+		}
+		w.Write([]byte(`{"host":"` + strings.TrimPrefix(r.URL.Path, "/dns/") + `","a":["10.0.0.1"]}`))
+	}))
+	defer srv.Close()
+
+	var work = client.Worker{
+		Host:       srv.URL,
+		AuthHeader: func(*http.Request) {},
+		Path:       "dns",
+		Workers:    8,
+		Coalesce:   true,
+	}
+	work.Connect(t.Context())
+	go func() {
+		defer work.Done()
+		for _, host := range []string{"one.com", "one.com", "one.com", "two.com", "one.com", "two.com"} {
+			work.Inbox <- job.NewDNS(host)
+		}
+	}()
+	var count int
+	for j := range work.Outbox {
+		if r := j.Unpack().(job.DNS); !j.Okay() || len(r.A) != 1 {
+			t.Fatal("expected shared result", r)
+		}
+		count++
+	}
+	if count != 6 || n.Load() != 2 {
+		t.Fatal("expected 6 jobs from 2 requests", count, n.Load())
+	}
+
+	n.Store(0)
+	var bulk = client.Worker{
+		Host:       srv.URL,
+		AuthHeader: func(*http.Request) {},
+		Path:       "dns",
+		Size:       4,
+		Body:       client.BodyJSON,
+		Coalesce:   true,
+	}
+	bulk.Connect(t.Context())
+	defer bulk.Done()
+	r, err := bulk.DoBatch(t.Context(), client.Jobs{job.NewDNS("one.com"), job.NewDNS("one.com"), job.NewDNS("two.com")})
+	if err != nil || len(r) != 2 || n.Load() != 1 {
+		t.Fatal("expected a single request", err, n.Load())
+	}
+}
+
+// go test -v client/client_test.go --run=COALESCEPAYLOAD
+func TestCOALESCEPAYLOAD(t *testing.T) {
+
+	// in-flight jobs share a request only when their Payload request
+	// options match
+
+	var n atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n.Add(1)
+		time.Sleep(time.Millisecond * 100) // keep the request in flight
+		var req []job.RdapJob
+		json.NewDecoder(r.Body).Decode(&req)
+		resp := make([]job.Rdap, len(req))
+		for i := range req {
+			resp[i] = job.Rdap{UUID: req[i].UUID, Host: req[i].Host}
+			if req[i].Full {
+				resp[i].Domain = &job.RdapDomain{Handle: "full"}
+			}
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer srv.Close()
+
+	var work = client.Worker{
+		Host:       srv.URL,
+		AuthHeader: func(*http.Request) {},
+		Path:       "rdap",
+		FullURL:    true,
+		Workers:    4,
+		Body:       client.BodyJSON,
+		Coalesce:   true,
+	}
+	work.Connect(t.Context())
+	go func() {
+		defer work.Done()
+		for _, full := range []bool{false, true, false, true} {
+			j := job.NewRdap("one.com")
+			j.Full = full
+			work.Inbox <- j
+		}
+	}()
+	var full int
+	for j := range work.Outbox {
+		if r := j.Unpack().(job.Rdap); r.Domain != nil {
+			full++
+		}
+	}
+	if full != 2 || n.Load() != 2 {
+		t.Fatal("expected a request per request options", full, n.Load())
+	}
+}
+
+// go test -v client/client_test.go --run=JOURNAL
+func TestJOURNAL(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/dns/slow.com" {
+			<-r.Context().Done() // interrupted run
+			return
+		}
+		w.Write([]byte(`{"host":"` + strings.TrimPrefix(r.URL.Path, "/dns/") + `"}`))
+	}))
+	defer srv.Close()
+
+	name := t.TempDir() + "/dns.journal"
+	journal, err := client.OpenJournal(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var work = client.Worker{
+		Host:       srv.URL,
+		AuthHeader: func(*http.Request) {},
+		Path:       "dns",
+		Journal:    journal,
+	}
+	work.Connect(t.Context())
+	for _, host := range []string{"one.com", "slow.com", "two.com", "Example.COM."} {
+		work.Submit(t.Context(), job.NewDNS(host))
+	}
+	go func() {
+		for range work.Outbox {
+		}
+	}()
+	time.Sleep(time.Millisecond * 100)
+	ctx, cancel := context.WithTimeout(t.Context(), time.Millisecond*50)
+	defer cancel()
+	if lost, _ := work.Shutdown(ctx); len(lost) != 1 {
+		t.Fatal("expected slow.com unprocessed", lost)
+	}
+	journal.Close()
+
+	if journal, err = client.OpenJournal(name); err != nil {
+		t.Fatal(err)
+	}
+	defer journal.Close()
+	if pending := journal.Pending(); len(pending) != 1 || pending[0] != "slow.com" {
+		t.Fatal("expected slow.com pending", pending)
+	}
+	if !journal.Seen("one.com") || !journal.Seen("slow.com") || !journal.Seen("Example.COM.") || journal.Seen("three.com") {
+		t.Fatal("unexpected seen state")
+	}
+}
+
+// go test -v client/client_test.go --run=PRIORITY
+func TestPRIORITY(t *testing.T) {
+
+	// ui.com is queued behind a bulk backlog and is served within the
+	// scheduling round; at most the jobs the workers already hold and the
+	// one bulk credit of the 8:4:1 weights go before it
+
+	var served atomic.Int64
+	seen := make(chan string, 64)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := strings.TrimPrefix(r.URL.Path, "/dns/")
+		served.Add(1)
+		seen <- host
+		time.Sleep(time.Millisecond * 5)
+		w.Write([]byte(`{"host":"` + host + `"}`))
+	}))
+	defer srv.Close()
+
+	const workers = 2
+	var work = client.Worker{
+		Host:       srv.URL,
+		AuthHeader: func(*http.Request) {},
+		Path:       "dns",
+		Workers:    workers,
+		Pacer:      time.Millisecond,
+	}
+	work.Connect(t.Context())
+	go func() {
+		for range work.Outbox {
+		}
+	}()
+
+	bulk := client.WithPriority(t.Context(), client.Bulk)
+	queued := make(chan int64, 1)
+	go func() {
+		for i := range 40 {
+			work.Submit(bulk, job.NewDNS("bulk"+strconv.Itoa(i)+".com"))
+		}
+		queued <- served.Load()
+		work.Submit(client.WithPriority(t.Context(), client.Interactive), job.NewDNS("ui.com"))
+		work.Submit(bulk, job.NewDNS("last.com"))
+		work.Done()
+	}()
+
+	// the bulk backlog is at least the lane capacity when ui.com is queued
+	var order []string
+	for host := range seen {
+		if order = append(order, host); host == "last.com" {
+			break
+		}
+	}
+	before := <-queued
+	for i := range order {
+		if order[i] == "ui.com" && int64(i) <= before+workers+1 && i < len(order)-2 {
+			return
+		}
+	}
+	t.Fatal("expected ui.com within the scheduling round", before, order)
+}
+
+// go test -v client/client_test.go --run=INPUT
+func TestINPUT(t *testing.T) {
+
+	for _, c := range []struct {
+		kind      int
+		key, want string
+	}{
+		{client.InputHost, "HTTPS://Bücher.Example.COM./path", "xn--bcher-kva.example.com"},
+		{client.InputHost, "münchen.de", "xn--mnchen-3ya.de"},
+		{client.InputHost, "ｅｘａｍｐｌｅ.com", "example.com"},
+		{client.InputHost, "xn--mnchen-3ya.de", "xn--mnchen-3ya.de"},
+		{client.InputHost, "xn--zz-abc.com", ""},
+		{client.InputHost, "a\ufffdb.com", ""},
+		{client.InputHost, "[2001:DB8::1]", "2001:db8::1"},
+		{client.InputHost, "10.0.0.1:443", "10.0.0.1"},
+		{client.InputHost, "_dmarc.zxdev.com", "_dmarc.zxdev.com"},
+		{client.InputHostPort, "Example.com:8443", "example.com:8443"},
+		{client.InputHosts, "1.2.3.4, Example.com.", "1.2.3.4,example.com"},
+		{client.InputURL, "Example.com/a b?q=1", "http://example.com/a%20b?q=1"},
+		{client.InputURL, "HTTPS://user@Example.com:8443/x", "https://example.com:8443/x"},
+		{client.InputHost, "-bad.com", ""},
+		{client.InputHost, "exa mple.com", ""},
+		{client.InputHost, "a..com", ""},
+		{client.InputURL, "ftp://zxdev.com", ""},
+		{client.InputHostPort, "zxdev.com:99999", ""},
+	} {
+		got, err := client.Normalize(c.kind, c.key)
+		if got != c.want || (err != nil) != (c.want == "") {
+			t.Fatal("normalize", c.key, got, err)
+		}
+	}
+
+	var paths = make(chan string, 4)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths <- r.URL.EscapedPath()
+		w.Write([]byte(`{"host":"zxdev.com"}`))
+	}))
+	defer srv.Close()
+
+	var work = client.Worker{
+		Host:       srv.URL,
+		AuthHeader: func(*http.Request) {},
+		Path:       "dns",
+	}
+	work.Connect(t.Context())
+	defer work.Done()
+
+	if _, err := work.Do(t.Context(), job.NewDNS("bad host/")); !errors.Is(err, client.ErrInvalid) || len(paths) != 0 {
+		t.Fatal("expected rejected job", err)
+	}
+	r, err := work.Do(t.Context(), job.NewDNS("https://ZXDEV.com./index.html"))
+	if err != nil || r.Request() != "zxdev.com" || <-paths != "/dns/zxdev.com" {
+		t.Fatal("expected normalized request", r.Request(), err)
+	}
+}
+
+// go test -v client/client_test.go --run=STREAM
+func TestSTREAM(t *testing.T) {
+
+	got := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.NewResponseController(w).EnableFullDuplex() // respond while reading
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		enc := json.NewEncoder(w)
+		dec := json.NewDecoder(r.Body)
+		for i := 0; ; i++ {
+			var item job.CRTSH
+			if dec.Decode(&item) != nil {
+				return
+			}
+			enc.Encode(item)
+			w.(http.Flusher).Flush()
+			if i == 0 {
+				select {
+				case <-got: // the first item was emitted before the response completed
+				case <-time.After(time.Second * 2):
+					t.Error("expected the first item before the response completed")
+				}
+			}
+		}
+	}))
+	defer srv.Close()
+
+	var work = client.Worker{
+		Host:       srv.URL,
+		AuthHeader: func(*http.Request) {},
+		Path:       "crtsh",
+		Size:       3,
+		Workers:    1,
+		Body:       client.BodyNDJSON,
+		Stream:     true,
+	}
+	work.Connect(t.Context())
+	go func() {
+		defer work.Done()
+		for _, host := range []string{"one.com", "two.com", "three.com"} {
+			work.Inbox <- job.NewCRTSH(host)
+		}
+	}()
+
+	var hosts []string
+	for j := range work.Outbox {
+		if !j.Okay() {
+			t.Fatal("unexpected failure", j.Err())
+		}
+		if hosts = append(hosts, j.Request()); len(hosts) == 1 {
+			close(got)
+		}
+	}
+	if len(hosts) != 3 {
+		t.Fatal("expected 3 jobs", hosts)
+	}
+}
+
+// go test -v client/client_test.go --run=COMPRESS
+func TestCOMPRESS(t *testing.T) {
+
+	// the server decodes the request body and encodes the response with the
+	// request coding when it is accepted
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		coding := r.Header.Get("Content-Encoding")
+		if !strings.Contains(r.Header.Get("Accept-Encoding"), coding) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var body io.Reader
+		var zw io.WriteCloser
+		switch coding {
+		case "gzip":
+			zr, err := gzip.NewReader(r.Body)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			body, zw = zr, gzip.NewWriter(w)
+		case "zstd":
+			zr, err := zstd.NewReader(r.Body)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			defer zr.Close()
+			body = zr
+			zw, _ = zstd.NewWriter(w)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var req []job.Hval
+		json.NewDecoder(body).Decode(&req)
+		for i := range req {
+			req[i].Head = []job.HHeader{{Url: req[i].Item, TLS: strings.Repeat("TLS_AES_128_GCM_SHA256 ", 20)}}
+		}
+		w.Header().Set("Content-Encoding", coding)
+		json.NewEncoder(zw).Encode(req)
+		zw.Close()
+	}))
+	defer srv.Close()
+
+	for _, c := range []struct {
+		coding string
+		level  int
+	}{{"gzip", gzip.BestCompression}, {"zstd", 0}, {"zstd", 19}} {
+
+		var registry client.Registry
+		var work = client.Worker{
+			Host:       srv.URL,
+			AuthHeader: func(*http.Request) {},
+			Path:       "hval",
+			Size:       50,
+			Body:       client.BodyJSON,
+			Compress:   &client.Compression{Request: c.coding, Level: c.level},
+			Metrics:    &registry,
+		}
+		work.Connect(t.Context())
+		var batch client.Jobs
+		for i := range 50 {
+			batch = append(batch, job.NewHval("https://zxdev.com/page/"+strconv.Itoa(i)))
+		}
+		_, err := work.DoBatch(t.Context(), batch)
+		work.Done()
+		if err != nil || len(batch[49].Unpack().(job.Hval).Head) != 1 {
+			t.Fatal("expected compressed exchange", c.coding, err)
+		}
+		for _, direction := range []string{"sent", "received"} {
+			labels := map[string]string{"path": "hval", "direction": direction}
+			wire, body := registry.Value("worker_wire_bytes_total", labels), registry.Value("worker_body_bytes_total", labels)
+			if wire == 0 || wire*2 > body {
+				t.Fatal("expected wire savings", c.coding, direction, wire, body)
+			}
+		}
+	}
+
+	var br = client.Worker{
+		Host:       srv.URL,
+		AuthHeader: func(*http.Request) {},
+		Path:       "hval",
+		Size:       2,
+		Compress:   &client.Compression{Request: "br"},
+	}
+	br.Connect(t.Context())
+	defer br.Done()
+	if _, err := br.Do(t.Context(), job.NewHval("zxdev.com")); err == nil {
+		t.Fatal("expected unknown coding failure without a br Codec")
+	}
+}
+
+// go test -v client/client_test.go --run=CONFIG
+func TestCONFIG(t *testing.T) {
+
+	// the same settings in every format; dns uses its POST profile and
+	// title its GET profile
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"worker.json": `{
+			"host": "http://localhost:1455", "secret": "` + secret + `", "pacer": "20ms",
+			"retry": {"attempts": 4, "base": "200ms", "status": [503]},
+			"profiles": {"dns": {"size": 100, "body": "ndjson"}, "title": {"method": "GET", "workers": 2}}
+		}`,
+		"worker.yaml": `
+host: http://localhost:1455 # cluster
+secret: "` + secret + `"
+pacer: 20ms
+retry:
+  attempts: 4
+  base: 200ms
+  status: [503]
+profiles:
+  dns:
+    size: 100
+    body: ndjson
+  title:
+    method: GET
+    workers: 2
+`,
+		"worker.toml": `
+host = "http://localhost:1455" # cluster
+secret = "` + secret + `"
+pacer = "20ms"
+
+[retry]
+attempts = 4
+base = "200ms"
+status = [503]
+
+[profiles.dns]
+size = 100
+body = "ndjson"
+
+[profiles.title]
+method = "GET"
+workers = 2
+`,
+	}
+
+	for name, content := range files {
+		file := filepath.Join(dir, name)
+		os.WriteFile(file, []byte(content), 0644)
+
+		w, err := client.LoadConfig(file, "dns")
+		if err != nil {
+			t.Fatal(name, err)
+		}
+		if w.Host != "http://localhost:1455" || w.Secret != secret || w.Pacer != time.Millisecond*20 ||
+			w.Size != 100 || w.Body != client.BodyNDJSON || w.Path != "dns" ||
+			w.Retry == nil || w.Retry.Attempts != 4 || w.Retry.Base != time.Millisecond*200 || w.Retry.Status[0] != 503 {
+			t.Fatalf("%s: unexpected dns config %+v", name, w)
+		}
+		if w, err = client.LoadConfig(file, "title"); err != nil || w.Size != 0 || w.Workers != 2 {
+			t.Fatal(name, "unexpected title config", w, err)
+		}
+	}
+
+	// schemeless hosts and params as accepted by Connect
+	cluster := filepath.Join(dir, "cluster.json")
+	os.WriteFile(cluster, []byte(`{"hosts": ["10.0.0.1:1455", "10.0.0.2:1455"], "params": "15"}`), 0644)
+	if w, err := client.LoadConfig(cluster, "dns"); err != nil || w.Params != "?15" || w.Hosts[0] != "10.0.0.1:1455" {
+		t.Fatal("expected Connect defaults", w, err)
+	}
+
+	// environment overrides with the endpoint setting first
+	t.Setenv("WORKER_PACER", "5ms")
+	t.Setenv("WORKER_DNS_SIZE", "10")
+	t.Setenv("WORKER_RETRY_ATTEMPTS", "2")
+	t.Setenv("WORKER_HOSTS", "http://one:1455, http://two:1455")
+	w, err := client.LoadConfig(filepath.Join(dir, "worker.yaml"), "dns")
+	if err != nil || w.Pacer != time.Millisecond*5 || w.Size != 10 || w.Retry.Attempts != 2 || len(w.Hosts) != 2 {
+		t.Fatal("expected environment overrides", w, err)
+	}
+	if w, err = client.LoadConfig("", "rdap"); err != nil || w.Size != 0 || w.Path != "rdap" {
+		t.Fatal("expected environment only config", w, err)
+	}
+
+	// validation
+	t.Setenv("WORKER_TITLE_SIZE", "50")
+	if _, err = client.LoadConfig(filepath.Join(dir, "worker.json"), "title"); !errors.Is(err, client.ErrConfig) ||
+		!strings.Contains(err.Error(), "requires POST") {
+		t.Fatal("expected size with GET failure", err)
+	}
+	for _, content := range []string{
+		`{"sise": 10}`,
+		`{"flush": "fast"}`,
+		`{"flush": 100}`,
+		`{"retry": {"base": 200}}`,
+		`{"body": "xml"}`,
+		`{"host": "localhost:1455", "params": "full=true", "method": "PUT"}`,
+	} {
+		file := filepath.Join(dir, "bad.json")
+		os.WriteFile(file, []byte(content), 0644)
+		if _, err = client.LoadConfig(file, "dns"); !errors.Is(err, client.ErrConfig) {
+			t.Fatal("expected validation failure", content, err)
+		}
+	}
+}
+
+// go test -v client/client_test.go --run=SECRETS
+func TestSECRETS(t *testing.T) {
+
+	// the client rotates to the next secret before the cluster does and
+	// falls back to the old one until the cluster rotates as well
+
+	const next = "ABCDEFGHIJKLMNOPQRSTUVWXYZ234567"
+
+	var handler atomic.Value
+	cluster := func(secret string) {
+		handler.Store(passkey.NewServer(t.Context(), secret).IsValid(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == "POST" {
+				w.Write([]byte(`[{"host":"one.com"},{"host":"two.com"}]`))
+				return
+			}
+			w.Write([]byte(`{"host":"` + strings.TrimPrefix(r.URL.Path, "/dns/") + `"}`))
+		})))
+	}
+	cluster(secret)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.Load().(http.Handler).ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	file := filepath.Join(t.TempDir(), "secret")
+	os.WriteFile(file, []byte(secret+"\n"), 0600)
+
+	var registry client.Registry
+	secrets := &client.Secrets{Source: client.SecretFile{Path: file}, Refresh: time.Millisecond * 5}
+	var work = client.Worker{Host: srv.URL, Path: "dns", Secrets: secrets, Metrics: &registry}
+	work.Connect(t.Context())
+	defer work.Done()
+	var bulk = client.Worker{Host: srv.URL, Path: "dns", Size: 2, Secrets: secrets}
+	bulk.Connect(t.Context())
+	defer bulk.Done()
+
+	lookup := func(stage string) {
+		if _, err := work.Do(t.Context(), job.NewDNS("one.com")); err != nil {
+			t.Fatal(stage, "GET", err)
+		}
+		if _, err := bulk.DoBatch(t.Context(), client.Jobs{job.NewDNS("one.com"), job.NewDNS("two.com")}); err != nil {
+			t.Fatal(stage, "POST", err)
+		}
+	}
+	lookup("initial")
+
+	// hot reload of the file; the cluster still uses the old secret
+	os.WriteFile(file, []byte(next), 0600)
+	wait := func(cond func() bool) {
+		for deadline := time.Now().Add(time.Second * 2); !cond(); time.Sleep(time.Millisecond) {
+			if time.Now().After(deadline) {
+				t.Fatal("timeout")
+			}
+		}
+	}
+	labels := map[string]string{"path": "dns"}
+	wait(func() bool { return registry.Value("worker_secret_rotations_total", labels) > 0 })
+	lookup("client rotated")
+	if registry.Value("worker_secret_fallbacks_total", labels) == 0 {
+		t.Fatal("expected the old secret fallback")
+	}
+
+	// the cluster rotates
+	cluster(next)
+	lookup("cluster rotated")
+	lookup("cluster rotated again")
+	if secrets.Err() != nil {
+		t.Fatal(secrets.Err())
+	}
+
+	// environment and command sources
+	t.Setenv("WORKER_TEST_SECRET", next)
+	if s, err := (client.SecretEnv{Name: "WORKER_TEST_SECRET"}).Secret(t.Context()); err != nil || s != next {
+		t.Fatal("expected env secret", s, err)
+	}
+	if s, err := (client.SecretExec{Command: []string{"echo", next}}).Secret(t.Context()); err != nil || s != next {
+		t.Fatal("expected exec secret", s, err)
+	}
+	os.WriteFile(file, []byte("not a secret"), 0600)
+	wait(func() bool { return secrets.Err() != nil })
+	lookup("invalid secret ignored")
+}
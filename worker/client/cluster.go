@@ -0,0 +1,203 @@
+package client
+
+import (
+	"context"
+	"hash/fnv"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Node is a worker cluster endpoint
+type Node struct {
+	URL string // scheme://host:port/path endpoint
+
+	root        string       // scheme://host:port for health probes
+	outstanding atomic.Int64 // in-flight requests
+	failures    atomic.Int32 // consecutive failures
+	down        atomic.Bool  // ejected from rotation
+}
+
+// Outstanding returns the number of in-flight requests on the node
+func (n *Node) Outstanding() int64 { return n.outstanding.Load() }
+
+// Healthy reports whether the node is in rotation
+func (n *Node) Healthy() bool { return !n.down.Load() }
+
+// Balancer selects the node for a request key from the healthy nodes;
+// POST batches use the request key of the first job in the batch
+type Balancer interface {
+	Pick(key string, nodes []*Node) *Node
+}
+
+// RoundRobin Balancer rotates through the nodes
+type RoundRobin struct{ n atomic.Uint64 }
+
+func (b *RoundRobin) Pick(key string, nodes []*Node) *Node {
+	return nodes[(b.n.Add(1)-1)%uint64(len(nodes))]
+}
+
+// LeastOutstanding Balancer selects the node with the fewest in-flight requests
+type LeastOutstanding struct{}
+
+func (LeastOutstanding) Pick(key string, nodes []*Node) *Node {
+	node := nodes[0]
+	for _, n := range nodes[1:] {
+		if n.Outstanding() < node.Outstanding() {
+			node = n
+		}
+	}
+	return node
+}
+
+// ConsistentHash Balancer maps a request key to the same node while the node
+// is healthy using rendezvous hashing so ejecting a node only moves its keys
+type ConsistentHash struct{}
+
+func (ConsistentHash) Pick(key string, nodes []*Node) *Node {
+	var node *Node
+	var best uint64
+	for _, n := range nodes {
+		h := fnv.New64a()
+		h.Write([]byte(n.URL))
+		h.Write([]byte{0})
+		h.Write([]byte(key))
+		if v := h.Sum64(); node == nil || v > best {
+			node, best = n, v
+		}
+	}
+	return node
+}
+
+// Health configures the node health checking used when the Worker has more
+// than one node; nodes are ejected after consecutive transport or 5xx
+// failures and returned to rotation when a periodic probe succeeds
+type Health struct {
+	Interval time.Duration // probe interval; default 10s
+	Path     string        // probe path on the node root; default /
+	Failures int           // consecutive failures before ejection; default 3
+}
+
+// configure applies the Health default settings
+func (h *Health) configure() {
+	if h.Interval == 0 {
+		h.Interval = time.Second * 10
+	}
+	if len(h.Path) == 0 {
+		h.Path = "/"
+	}
+	if !strings.HasPrefix(h.Path, "/") {
+		h.Path = "/" + h.Path
+	}
+	if h.Failures == 0 {
+		h.Failures = 3
+	}
+}
+
+// endpoint returns the normalized scheme://host:port/path endpoint
+func endpoint(host, path string) string {
+	if !strings.HasPrefix(host, "http://") && !strings.HasPrefix(host, "https://") {
+		host = "http://" + host
+	}
+	host = strings.TrimSuffix(host, "/")
+	if len(path) > 0 {
+		host += "/" + strings.TrimPrefix(path, "/")
+	}
+	return strings.TrimSuffix(host, "/")
+}
+
+// newNode returns the Node for the host and endpoint path segment
+func newNode(host, path string) *Node {
+	node := &Node{URL: endpoint(host, path), root: endpoint(host, "")}
+	if u, err := url.Parse(node.root); err == nil {
+		node.root = u.Scheme + "://" + u.Host
+	}
+	return node
+}
+
+// Nodes returns the worker cluster nodes
+func (w *Worker) Nodes() []*Node { return w.nodes }
+
+// pick returns a healthy node not yet tried for the request key; every node
+// is a candidate on the first try when none are healthy and nil is returned
+// when the retry candidates are exhausted
+func (w *Worker) pick(key string, tried []*Node) *Node {
+
+	nodes := make([]*Node, 0, len(w.nodes))
+	for _, n := range w.nodes {
+		if n.Healthy() && !slices.Contains(tried, n) {
+			nodes = append(nodes, n)
+		}
+	}
+	if len(nodes) == 0 {
+		if len(tried) > 0 {
+			return nil
+		}
+		nodes = w.nodes
+	}
+
+	return w.Balancer.Pick(key, nodes)
+}
+
+// observe tracks consecutive node failures and ejects a failing node
+func (w *Worker) observe(node *Node, err *Error) {
+
+	if err == nil || err.StatusCode < 500 && (err.StatusCode != 0 || err.Err == nil) {
+		node.failures.Store(0)
+		return
+	}
+	if w.Health != nil && int(node.failures.Add(1)) >= w.Health.Failures {
+		node.down.Store(true)
+	}
+}
+
+// failover reports whether the request may succeed on another node
+func (e *Error) failover() bool {
+	switch {
+	case e.StatusCode == 0:
+		return e.Err != nil
+	case e.Err != nil:
+		return false
+	}
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// probe periodically checks every node; a node is returned to rotation when
+// it responds without a server error and counts a failure otherwise
+func (w *Worker) probe(ctx context.Context) {
+
+	ticker := time.NewTicker(w.Health.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for _, n := range w.nodes {
+			req, err := http.NewRequestWithContext(ctx, "GET", n.root+w.Health.Path, nil)
+			if err != nil {
+				continue
+			}
+			resp, err := w.rt(req)
+			if err != nil {
+				if ctx.Err() == nil {
+					w.observe(n, &Error{Err: err})
+				}
+				continue
+			}
+			resp.Body.Close()
+			if resp.StatusCode >= 500 {
+				w.observe(n, &Error{StatusCode: resp.StatusCode})
+				continue
+			}
+			n.failures.Store(0)
+			n.down.Store(false)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+)
+
+// flight is an in-flight request shared by the jobs with the same cache
+// key; the worker.Path, worker.Params, job.Request(), and Payload options
+type flight struct {
+	job  Job             // leader job making the request
+	done chan struct{}   // closed when the leader job is complete
+	data json.RawMessage // leader result snapshot taken by land
+	err  error           // leader failure
+}
+
+// passenger is a job waiting on the flight of its leader job
+type passenger struct {
+	job Job
+	f   *flight
+}
+
+// board returns the jobs that lead a new flight and the passenger jobs that
+// join a flight already in progress, including one led from the same batch
+func (w *Worker) board(jobs Jobs) (Jobs, []passenger) {
+
+	var leaders Jobs
+	var passengers []passenger
+
+	w.flightMu.Lock()
+	defer w.flightMu.Unlock()
+
+	if w.flights == nil {
+		w.flights = make(map[string]*flight)
+	}
+	for i := range jobs {
+		key := w.cacheKey(jobs[i])
+		if f, ok := w.flights[key]; ok {
+			passengers = append(passengers, passenger{job: jobs[i], f: f})
+			continue
+		}
+		w.flights[key] = &flight{job: jobs[i], done: make(chan struct{})}
+		leaders = append(leaders, jobs[i])
+	}
+
+	return leaders, passengers
+}
+
+// land completes the flights led by jobs
+func (w *Worker) land(jobs ...Job) {
+
+	w.flightMu.Lock()
+	defer w.flightMu.Unlock()
+
+	for i := range jobs {
+		key := w.cacheKey(jobs[i])
+		if f, ok := w.flights[key]; ok && f.job == jobs[i] {
+			// snapshot the result as a streamed leader job may already be
+			// owned by the worker.Outbox receiver
+			f.data, _ = json.Marshal(jobs[i])
+			f.err = jobs[i].Err()
+			delete(w.flights, key)
+			close(f.done)
+		}
+	}
+}
+
+// await waits for the passenger flight and copies the leader result; false
+// is returned when the leader request was canceled while ctx was not so that
+// the passenger job must make its own request
+func (w *Worker) await(ctx context.Context, method string, p passenger) bool {
+
+	select {
+	case <-p.f.done:
+	case <-ctx.Done():
+		fail(p.job, &Error{Method: method, URL: w.Host + w.Params, Err: ctx.Err()})
+		return true
+	}
+
+	err := p.f.err
+	if ctx.Err() == nil && (errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)) {
+		return false
+	}
+	clone(p.job, p.f.data, p.f.err)
+	w.count("worker_coalesced_total", 1, "method", method)
+
+	return true
+}
+
+// clone copies the result snapshot and failure onto dst while keeping the
+// dst job UUID
+func clone(dst Job, data json.RawMessage, err error) {
+
+	if len(data) > 0 {
+		var id uint64
+		t, tracked := dst.(Tracker)
+		if tracked {
+			id = t.ID()
+		}
+		json.Unmarshal(data, dst)
+		if tracked {
+			t.SetID(id)
+		}
+	}
+
+	var e *Error
+	if errors.As(err, &e) {
+		fail(dst, e)
+	} else if err != nil {
+		dst.Fail(StatusFailed, err)
+	}
+}
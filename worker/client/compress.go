@@ -0,0 +1,187 @@
+package client
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Codec is a http content coding used to compress the POST request bodies
+// and decode the responses; Gzip and Zstd are built-in while other codings
+// are plugged in by implementing Codec
+type Codec interface {
+	Name() string                                             // content coding token; gzip, zstd
+	NewWriter(w io.Writer, level int) (io.WriteCloser, error) // level 0 is the codec default
+	NewReader(r io.Reader) (io.ReadCloser, error)
+}
+
+var (
+	// built-in content codings
+	Gzip Codec = gzipCodec{} // levels 1-9
+	Zstd Codec = zstdCodec{} // levels 1-22 mapped to the nearest encoder speed
+)
+
+type gzipCodec struct{}
+
+func (gzipCodec) Name() string { return "gzip" }
+func (gzipCodec) NewWriter(w io.Writer, level int) (io.WriteCloser, error) {
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+	return gzip.NewWriterLevel(w, level)
+}
+func (gzipCodec) NewReader(r io.Reader) (io.ReadCloser, error) { return gzip.NewReader(r) }
+
+type zstdCodec struct{} // single goroutine streams; one body per request
+
+func (zstdCodec) Name() string { return "zstd" }
+func (zstdCodec) NewWriter(w io.Writer, level int) (io.WriteCloser, error) {
+	speed := zstd.SpeedDefault
+	if level != 0 {
+		speed = zstd.EncoderLevelFromZstd(level)
+	}
+	return zstd.NewWriter(w, zstd.WithEncoderLevel(speed), zstd.WithEncoderConcurrency(1))
+}
+func (zstdCodec) NewReader(r io.Reader) (io.ReadCloser, error) {
+	d, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+	return d.IOReadCloser(), nil
+}
+
+// Compression configuration for the Worker; responses are negotiated with
+// every Codec while POST request bodies are compressed when Request is set
+//
+// The wire and decoded body sizes are recorded in the worker.Metrics as
+// worker_wire_bytes_total and worker_body_bytes_total by direction so the
+// savings are the difference of the two
+type Compression struct {
+	Request string  // POST request body content coding; default none
+	Level   int     // request compression level; 0 is the codec default
+	Codecs  []Codec // response codings in preference order; default Zstd, Gzip
+
+	request Codec  // Request codec
+	accept  string // Accept-Encoding header
+	err     error  // configuration failure reported on every request
+}
+
+// configure applies the Compression default settings
+func (c *Compression) configure() {
+
+	if len(c.Codecs) == 0 {
+		c.Codecs = []Codec{Zstd, Gzip}
+	}
+
+	names := make([]string, len(c.Codecs))
+	for i := range c.Codecs {
+		names[i] = c.Codecs[i].Name()
+		if strings.EqualFold(names[i], c.Request) {
+			c.request = c.Codecs[i]
+		}
+	}
+	c.accept = strings.Join(names, ", ")
+	if len(c.Request) > 0 && c.request == nil {
+		c.err = fmt.Errorf("compression: unknown request coding %q", c.Request)
+	}
+}
+
+// codec returns the Codec for the content coding
+func (c *Compression) codec(name string) Codec {
+	for i := range c.Codecs {
+		if strings.EqualFold(c.Codecs[i].Name(), name) {
+			return c.Codecs[i]
+		}
+	}
+	return nil
+}
+
+// counter counts the bytes read or written
+type counter struct {
+	r io.Reader
+	w io.Writer
+	n int64
+}
+
+func (c *counter) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (c *counter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// compress returns the compressed request body and its content coding;
+// the body is compressed while it is sent
+func (w *Worker) compress(rd io.Reader) (io.Reader, string) {
+
+	c := w.Compress
+	if c == nil || c.request == nil {
+		return rd, ""
+	}
+
+	r, pw := io.Pipe()
+	go func() {
+		wire := &counter{w: pw}
+		body := &counter{r: rd}
+		zw, err := c.request.NewWriter(wire, c.Level)
+		if err == nil {
+			_, err = io.Copy(zw, body)
+			if cerr := zw.Close(); err == nil {
+				err = cerr
+			}
+		}
+		if rc, ok := rd.(io.Closer); ok {
+			rc.Close()
+		}
+		w.count("worker_body_bytes_total", float64(body.n), "direction", "sent")
+		w.count("worker_wire_bytes_total", float64(wire.n), "direction", "sent")
+		pw.CloseWithError(err)
+	}()
+
+	return r, c.request.Name()
+}
+
+// decompress returns the decoded response body for the response
+// Content-Encoding and a done func that records the received sizes
+func (w *Worker) decompress(resp *http.Response) (io.Reader, func(), error) {
+
+	c := w.Compress
+	if c == nil {
+		return resp.Body, func() {}, nil
+	}
+
+	wire := &counter{r: resp.Body}
+	var body *counter
+	var zr io.ReadCloser
+	switch coding := resp.Header.Get("Content-Encoding"); coding {
+	case "", "identity":
+		body = wire
+	default:
+		codec := c.codec(coding)
+		if codec == nil {
+			return nil, nil, fmt.Errorf("compression: unsupported response coding %q", coding)
+		}
+		var err error
+		if zr, err = codec.NewReader(wire); err != nil {
+			return nil, nil, err
+		}
+		body = &counter{r: zr}
+	}
+
+	return body, func() {
+		if zr != nil {
+			zr.Close()
+		}
+		w.count("worker_body_bytes_total", float64(body.n), "direction", "received")
+		w.count("worker_wire_bytes_total", float64(wire.n), "direction", "received")
+	}, nil
+}
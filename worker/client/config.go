@@ -0,0 +1,323 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrConfig is wrapped by every LoadConfig validation failure
+var ErrConfig = errors.New("invalid config")
+
+// EnvPrefix is the environment variable prefix of the LoadConfig overrides
+const EnvPrefix = "WORKER_"
+
+// LoadConfig returns the Worker configured for the endpoint path from the
+// json, yaml, or toml file, by extension, and the environment; an empty file
+// uses the environment only. Settings are the Worker json names, durations
+// are strings with a unit such as "10ms" while bare numbers are rejected,
+// hosts default to http:// and params to a leading ? as with Connect, and
+// body and input accept the names text, json, ndjson and host, hostport,
+// hosts, url, raw
+//
+// The settings are applied in order of precedence from
+//
+//   - WORKER_{PATH}_{SETTING} variables, such as WORKER_DNS_SIZE=50
+//   - WORKER_{SETTING} variables, such as WORKER_PACER=20ms
+//   - the profiles.{path} section of the file
+//   - the top level settings of the file
+//
+// where nested settings join with an underscore (WORKER_RETRY_ATTEMPTS) and
+// lists are comma separated. The optional method setting, GET or POST,
+// declares the intended request method and a full_url:false is taken as
+// GET; every nonsensical combination, such as size>1 with GET, unknown
+// settings, and malformed values are reported together wrapping ErrConfig
+//
+//	{
+//	  "host": "http://wrk.netstar.dev:1455", "secret": "...", "pacer": "10ms",
+//	  "retry": {"attempts": 4, "base": "200ms"},
+//	  "profiles": {"dns": {"size": 100, "body": "ndjson"}, "title": {"method": "GET"}}
+//	}
+func LoadConfig(file, path string) (*Worker, error) {
+
+	settings := make(map[string]any)
+	if len(file) > 0 {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		switch ext := strings.ToLower(filepath.Ext(file)); ext {
+		case ".json":
+			dec := json.NewDecoder(bytes.NewReader(b))
+			dec.UseNumber()
+			err = dec.Decode(&settings)
+		case ".yaml", ".yml":
+			err = yaml.Unmarshal(b, &settings)
+		case ".toml":
+			err = toml.Unmarshal(b, &settings)
+		default:
+			err = fmt.Errorf("unsupported format %q", ext)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %w", ErrConfig, file, err)
+		}
+		if settings == nil {
+			settings = make(map[string]any) // null document
+		}
+	}
+
+	// overlay the endpoint profile and the environment
+	profiles, _ := settings["profiles"].(map[string]any)
+	delete(settings, "profiles")
+	if len(path) == 0 {
+		path, _ = settings["path"].(string)
+	}
+	if profile, ok := profiles[path].(map[string]any); ok {
+		merge(settings, profile)
+	}
+	prefixes := []string{EnvPrefix}
+	if len(path) > 0 {
+		prefixes = append(prefixes, EnvPrefix+strings.ToUpper(path)+"_")
+	}
+	for _, prefix := range prefixes {
+		environ(settings, reflect.TypeFor[Worker](), prefix)
+		if v, ok := os.LookupEnv(prefix + "METHOD"); ok {
+			settings["method"] = v
+		}
+	}
+	if len(path) > 0 {
+		settings["path"] = path
+	}
+
+	method, _ := settings["method"].(string)
+	delete(settings, "method")
+	if full, ok := settings["full_url"]; ok && len(method) == 0 {
+		if b, _ := strconv.ParseBool(fmt.Sprint(full)); !b {
+			method = "GET"
+		}
+	}
+
+	var errs []error
+	invalid := func(format string, a ...any) {
+		errs = append(errs, fmt.Errorf("%w: %s", ErrConfig, fmt.Sprintf(format, a...)))
+	}
+
+	v, err := coerce(reflect.TypeFor[Worker](), settings, "")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrConfig, err)
+	}
+	b, _ := json.Marshal(v)
+	w := new(Worker)
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err = dec.Decode(w); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrConfig, err)
+	}
+
+	// validation
+	for _, host := range append([]string{w.Host}, w.Hosts...) {
+		if len(host) == 0 {
+			continue
+		}
+		// a schemeless host:port is taken as http as by Connect
+		if u, err := url.Parse(endpoint(host, "")); err != nil || len(u.Host) == 0 {
+			invalid("host %q is not a host:port or http(s)://host:port url", host)
+		}
+	}
+	if len(w.Path) == 0 {
+		invalid("path is required")
+	}
+	if len(w.Params) > 0 && !strings.HasPrefix(w.Params, "?") {
+		w.Params = "?" + w.Params
+	}
+	if w.Workers < 0 || w.Size < 0 || w.Pacer < 0 || w.Flush < 0 {
+		invalid("workers, size, pacer, and flush must not be negative")
+	}
+	if w.Body < BodyText || w.Body > BodyNDJSON {
+		invalid("unknown body %d", w.Body)
+	}
+	if w.Input < 0 || w.Input > InputRaw {
+		invalid("unknown input %d", w.Input)
+	}
+	if r := w.Retry; r != nil && (r.Attempts < 0 || r.Base < 0 || r.Max < 0 || r.Jitter < 0 || r.Jitter > 1) {
+		invalid("retry attempts and backoff must not be negative and jitter is 0..1")
+	}
+	switch strings.ToUpper(method) {
+	case "":
+	case "GET":
+		if w.Size > 1 {
+			invalid("size %d requires POST; GET sends one request per job", w.Size)
+		}
+		if w.FullURL {
+			invalid("full_url requires POST")
+		}
+		if w.Body != BodyText || w.Stream {
+			invalid("body and stream apply to POST only")
+		}
+	case "POST":
+		w.FullURL = true
+	default:
+		invalid("unknown method %q", method)
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	return w, nil
+}
+
+// merge overlays src onto dst recursively
+func merge(dst, src map[string]any) {
+	for k, v := range src {
+		if m, ok := v.(map[string]any); ok {
+			if d, ok := dst[k].(map[string]any); ok {
+				merge(d, m)
+				continue
+			}
+		}
+		dst[k] = v
+	}
+}
+
+// environ overlays the prefixed environment variables for the json settings
+// of the struct type t onto settings
+func environ(settings map[string]any, t reflect.Type, prefix string) {
+
+	for i := range t.NumField() {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if !f.IsExported() || len(name) == 0 || name == "-" {
+			continue
+		}
+		key := prefix + strings.ToUpper(name)
+		if ft := f.Type; ft.Kind() == reflect.Pointer && ft.Elem().Kind() == reflect.Struct {
+			nested, ok := settings[name].(map[string]any)
+			if !ok {
+				nested = make(map[string]any)
+			}
+			if environ(nested, ft.Elem(), key+"_"); len(nested) > 0 {
+				settings[name] = nested
+			}
+			continue
+		}
+		if v, ok := os.LookupEnv(key); ok {
+			settings[name] = v
+		}
+	}
+}
+
+// settingNames are the Body and Input setting names
+var settingNames = map[string]map[string]int{
+	"body":  {"text": BodyText, "json": BodyJSON, "ndjson": BodyNDJSON},
+	"input": {"host": InputHost, "hostport": InputHostPort, "hosts": InputHosts, "url": InputURL, "raw": InputRaw},
+}
+
+// coerce converts the parsed setting v to the json form of type t; duration
+// strings, setting names, and the string values of the environment and the
+// files are converted while unknown struct settings are rejected
+func coerce(t reflect.Type, v any, name string) (any, error) {
+
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	s, isString := v.(string)
+	fail := func() (any, error) { return nil, fmt.Errorf("%s: invalid %s %q", name, t.Kind(), s) }
+
+	switch {
+	case t == reflect.TypeFor[time.Duration]():
+		if !isString {
+			return nil, fmt.Errorf("%s: duration %v requires a unit, such as \"%vms\"", name, v, v)
+		}
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		return int64(d), nil
+
+	case t.Kind() == reflect.Struct:
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%s: expected a table of settings", name)
+		}
+		fields := make(map[string]reflect.Type)
+		for i := range t.NumField() {
+			if tag, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ","); len(tag) > 0 && tag != "-" {
+				fields[tag] = t.Field(i).Type
+			}
+		}
+		out := make(map[string]any, len(m))
+		for k, v := range m {
+			ft, ok := fields[k]
+			if !ok {
+				return nil, fmt.Errorf("unknown setting %q", strings.TrimPrefix(name+"."+k, "."))
+			}
+			var err error
+			if out[k], err = coerce(ft, v, strings.TrimPrefix(name+"."+k, ".")); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+
+	case t.Kind() == reflect.Slice && isString:
+		var list []any
+		for item := range strings.SplitSeq(s, ",") {
+			list = append(list, strings.TrimSpace(item))
+		}
+		return coerce(t, list, name)
+
+	case t.Kind() == reflect.Slice:
+		list, ok := v.([]any)
+		if !ok {
+			return v, nil
+		}
+		out := make([]any, len(list))
+		for i := range list {
+			var err error
+			if out[i], err = coerce(t.Elem(), list[i], name); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+
+	case !isString || t.Kind() == reflect.String:
+		return v, nil
+
+	case t.Kind() == reflect.Int:
+		if n, ok := settingNames[name][strings.ToLower(s)]; ok {
+			return n, nil
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return fail()
+		}
+		return n, nil
+
+	case t.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fail()
+		}
+		return b, nil
+
+	case t.Kind() == reflect.Float64:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return fail()
+		}
+		return f, nil
+	}
+
+	return v, nil
+}
@@ -0,0 +1,212 @@
+package client
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+var (
+	// POST response correlation failures recorded on jobs
+	ErrMissing    = errors.New("response item missing")
+	ErrDuplicate  = errors.New("response item duplicated")
+	ErrUnexpected = errors.New("unexpected response items")
+)
+
+// Tracker is an optional Job extension exposing the job UUID used to
+// correlate POST response items with their originating jobs; all of the
+// job package types implement Tracker
+type Tracker interface {
+	ID() uint64      // job UUID
+	SetID(id uint64) // assign job UUID
+}
+
+// track assigns a unique UUID to every Tracker job that does not have one
+func (w *Worker) track(jobs Jobs) {
+	for i := range jobs {
+		if t, ok := jobs[i].(Tracker); ok && t.ID() == 0 {
+			t.SetID(w.uuid.Add(1))
+		}
+	}
+}
+
+// probe is the minimal view of a response item used for correlation;
+// the job package types echo the request key as host, url, or item
+type probe struct {
+	UUID uint64 `json:"uuid"`
+	Host string `json:"host"`
+	Url  string `json:"url"`
+	Item string `json:"item"`
+}
+
+func (p *probe) key() string {
+	for _, k := range []string{p.Host, p.Url, p.Item} {
+		if len(k) > 0 {
+			return normalKey(k)
+		}
+	}
+	return ""
+}
+
+// normalKey folds request keys that the server may normalize
+func normalKey(k string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(k)), ".")
+}
+
+// correlator matches the POST response items to their originating jobs
+type correlator struct {
+	jobs        Jobs
+	method, url string
+	byID        map[uint64]int
+	byKey       map[string][]int
+	matched     []int  // response items matched per job
+	accepted    []bool // jobs released by stream
+	unexpected  int
+}
+
+func newCorrelator(jobs Jobs, method, url string) *correlator {
+
+	c := &correlator{jobs: jobs, method: method, url: url,
+		byID:     make(map[uint64]int, len(jobs)),
+		byKey:    make(map[string][]int, len(jobs)),
+		matched:  make([]int, len(jobs)),
+		accepted: make([]bool, len(jobs)),
+	}
+	for i := range jobs {
+		if t, ok := jobs[i].(Tracker); ok && t.ID() != 0 {
+			c.byID[t.ID()] = i
+		}
+		k := normalKey(jobs[i].Request())
+		c.byKey[k] = append(c.byKey[k], i)
+	}
+
+	return c
+}
+
+// match decodes the item into its job by UUID, or by request key when the
+// server did not echo the UUID, and returns the job index on the first match
+func (c *correlator) match(item json.RawMessage) (int, bool) {
+
+	var p probe
+	if json.Unmarshal(item, &p) != nil {
+		c.unexpected++
+		return 0, false
+	}
+
+	i, ok := c.byID[p.UUID]
+	if !ok && p.UUID == 0 {
+		// uuid not echoed; use the first unmatched job for the request
+		// key or the first job for the key when all were matched
+		if n := c.byKey[p.key()]; len(n) > 0 {
+			i, ok = n[0], true
+			for _, j := range n {
+				if c.matched[j] == 0 {
+					i = j
+					break
+				}
+			}
+		}
+	}
+	if !ok {
+		c.unexpected++
+		return 0, false
+	}
+
+	if c.matched[i]++; c.matched[i] > 1 {
+		return 0, false
+	}
+	if err := json.Unmarshal(item, c.jobs[i]); err != nil {
+		fail(c.jobs[i], &Error{Method: c.method, URL: c.url, StatusCode: 200, Err: err})
+	}
+
+	return i, true
+}
+
+// finish fails the missing and duplicated jobs that were not accepted
+func (c *correlator) finish() {
+	for i := range c.jobs {
+		switch {
+		case c.accepted[i]:
+		case c.matched[i] == 0 && c.unexpected > 0:
+			fail(c.jobs[i], &Error{Method: c.method, URL: c.url, StatusCode: 200,
+				Err: fmt.Errorf("%w; %d %w", ErrMissing, c.unexpected, ErrUnexpected)})
+		case c.matched[i] == 0:
+			fail(c.jobs[i], &Error{Method: c.method, URL: c.url, StatusCode: 200, Err: ErrMissing})
+		case c.matched[i] > 1:
+			fail(c.jobs[i], &Error{Method: c.method, URL: c.url, StatusCode: 200, Err: ErrDuplicate})
+		}
+	}
+}
+
+// correlate decodes the POST response array and matches each item to its
+// originating job; missing and duplicated items fail their job while
+// unexpected items are reported on the missing jobs they may have belonged to
+// and their number is returned
+func correlate(r io.Reader, jobs Jobs, method, url string) (int, error) {
+
+	var items []json.RawMessage
+	if err := json.NewDecoder(r).Decode(&items); err != nil {
+		return 0, err
+	}
+
+	c := newCorrelator(jobs, method, url)
+	for _, item := range items {
+		c.match(item)
+	}
+	c.finish()
+
+	return c.unexpected, nil
+}
+
+// stream decodes a NDJSON or json array POST response item by item and
+// passes each job to accept as soon as its item is matched; an accepted job
+// is released to the caller and is not changed by any later item; the number
+// of unexpected items is returned as with correlate
+func stream(r io.Reader, jobs Jobs, method, url string, accept func(Job) bool) (int, error) {
+
+	br := bufio.NewReader(r)
+	var array bool
+	for {
+		b, err := br.Peek(1)
+		if err != nil {
+			break // empty response; every job is missing
+		}
+		if b[0] == ' ' || b[0] == '\t' || b[0] == '\r' || b[0] == '\n' {
+			br.ReadByte()
+			continue
+		}
+		array = b[0] == '['
+		break
+	}
+
+	dec := json.NewDecoder(br)
+	if array {
+		if _, err := dec.Token(); err != nil {
+			return 0, err
+		}
+	}
+
+	c := newCorrelator(jobs, method, url)
+	for !array || dec.More() {
+		var item json.RawMessage
+		if err := dec.Decode(&item); err == io.EOF && !array {
+			break
+		} else if err != nil {
+			return c.unexpected, err
+		}
+		if i, ok := c.match(item); ok {
+			c.accepted[i] = accept(jobs[i])
+		}
+	}
+	if array {
+		if _, err := dec.Token(); err != nil {
+			return c.unexpected, err
+		}
+	}
+	c.finish()
+
+	return c.unexpected, nil
+}
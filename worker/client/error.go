@@ -0,0 +1,52 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	// StatusFailed is the Job status recorded for a request that failed
+	// before a usable http response was received or decoded; http status
+	// failures record the http status code instead
+	StatusFailed = -1
+)
+
+// Error is the per job request failure recorded via Job.Fail so that
+// a failed request can never be mistaken for an empty successful result
+type Error struct {
+	Method     string        // GET|POST
+	URL        string        // request url
+	StatusCode int           // http status code; 0 when no response was received
+	Err        error         // transport, encode, or decode error; nil on http status failures
+	RetryAfter time.Duration // server Retry-After hint on http status failures
+}
+
+func (e *Error) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("worker: %s %s: %d %s", e.Method, e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("worker: %s %s: %v", e.Method, e.URL, e.Err)
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// Timeout reports whether the request failed due to a timeout
+func (e *Error) Timeout() bool {
+	var t interface{ Timeout() bool }
+	return errors.As(e.Err, &t) && t.Timeout()
+}
+
+// Status returns the Job status for the failure; the http status code
+// when the server responded otherwise StatusFailed
+func (e *Error) Status() int {
+	if e.Err == nil && e.StatusCode != 0 {
+		return e.StatusCode
+	}
+	return StatusFailed
+}
+
+// fail records err on job using the status derived from err
+func fail(job Job, err *Error) { job.Fail(err.Status(), err) }
@@ -0,0 +1,241 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
+)
+
+// request input kinds; see Worker.Input
+const (
+	InputHost     = iota + 1 // hostname or ip
+	InputHostPort            // hostname or ip with an optional :port
+	InputHosts               // comma separated hostname or ip list
+	InputURL                 // http(s) url or hostname
+	InputRaw                 // sent as is without normalization
+)
+
+// Inputs is the default request input kind by worker.Path; other paths use
+// InputRaw
+var Inputs = map[string]int{
+	"rdap":     InputHost,
+	"dns":      InputHost,
+	"crtsh":    InputHost,
+	"mail":     InputHost,
+	"cert":     InputHostPort,
+	"firewall": InputHosts,
+	"title":    InputURL,
+	"method":   InputURL,
+	"hval":     InputURL,
+}
+
+// ErrInvalid is the failure recorded on jobs rejected by the request
+// normalization before dispatch
+var ErrInvalid = errors.New("invalid request")
+
+// Rewriter is the optional Job interface used to replace the job request
+// key with its normalized form; without it a job is only validated
+type Rewriter interface {
+	SetRequest(key string)
+}
+
+// normalize validates and rewrites the job request key for the worker.Input
+// kind; hostnames are lowercased, IDN mapped and validated by UTS #46 and
+// converted to punycode, and trailing dots removed while the scheme and path are stripped for host-only
+// endpoints, which includes every GET endpoint. A rejected job is failed
+// with ErrInvalid and false is returned.
+func (w *Worker) normalize(job Job) bool {
+
+	kind := w.Input
+	if kind == InputRaw {
+		return true
+	}
+	if kind == InputURL && !w.FullURL {
+		kind = InputHost // GET .../method/{host}
+	}
+
+	key, err := Normalize(kind, job.Request())
+	if err != nil {
+		method := "GET"
+		if w.FullURL {
+			method = "POST"
+		}
+		fail(job, &Error{Method: method, URL: w.Host + w.Params, Err: err})
+		w.items(job)
+		return false
+	}
+	if r, ok := job.(Rewriter); ok && key != job.Request() {
+		r.SetRequest(key)
+	}
+
+	return true
+}
+
+// normalized rejects the invalid jobs and returns the valid jobs
+func (w *Worker) normalized(jobs Jobs) Jobs {
+
+	valid := make(Jobs, 0, len(jobs))
+	for i := range jobs {
+		if w.normalize(jobs[i]) {
+			valid = append(valid, jobs[i])
+		}
+	}
+
+	return valid
+}
+
+// Normalize returns the normalized request key for the input kind or an
+// error wrapping ErrInvalid
+func Normalize(kind int, key string) (string, error) {
+
+	key = strings.TrimSpace(key)
+	invalid := func(reason string) (string, error) {
+		return "", fmt.Errorf("%w: %q: %s", ErrInvalid, key, reason)
+	}
+	if len(key) == 0 {
+		return invalid("empty")
+	}
+
+	switch kind {
+	case InputHost, InputHostPort:
+		host, port, reason := hostPort(key)
+		if len(reason) > 0 {
+			return invalid(reason)
+		}
+		if len(port) > 0 && kind == InputHostPort {
+			return net.JoinHostPort(host, port), nil
+		}
+		return host, nil
+
+	case InputHosts:
+		items := strings.Split(key, ",")
+		for i := range items {
+			var reason string
+			if items[i], _, reason = hostPort(strings.TrimSpace(items[i])); len(reason) > 0 {
+				return invalid(reason)
+			}
+		}
+		return strings.Join(items, ","), nil
+
+	case InputURL:
+		if !strings.Contains(key, "://") {
+			if strings.ContainsAny(key, "/?#") {
+				return Normalize(InputURL, "http://"+key)
+			}
+			host, port, reason := hostPort(key)
+			if len(reason) > 0 {
+				return invalid(reason)
+			}
+			if len(port) > 0 {
+				return net.JoinHostPort(host, port), nil
+			}
+			return host, nil
+		}
+		u, err := url.Parse(key)
+		if err != nil {
+			return invalid("malformed url")
+		}
+		if u.Scheme = strings.ToLower(u.Scheme); u.Scheme != "http" && u.Scheme != "https" {
+			return invalid("unsupported scheme " + u.Scheme)
+		}
+		host, port, reason := hostPort(u.Host)
+		if len(reason) > 0 {
+			return invalid(reason)
+		}
+		if u.Host, u.User = host, nil; len(port) > 0 {
+			u.Host = net.JoinHostPort(host, port)
+		} else if strings.Contains(host, ":") {
+			u.Host = "[" + host + "]"
+		}
+		return u.String(), nil // path, query, and fragment properly escaped
+	}
+
+	return key, nil
+}
+
+// hostPort normalizes a host with an optional scheme, userinfo, path, and
+// port into the hostname or ip and port; reason is set when invalid
+func hostPort(s string) (host, port, reason string) {
+
+	if i := strings.Index(s, "://"); i >= 0 {
+		s = s[i+3:]
+	}
+	if i := strings.IndexAny(s, "/?#"); i >= 0 {
+		s = s[:i]
+	}
+	if i := strings.LastIndexByte(s, '@'); i >= 0 {
+		s = s[i+1:]
+	}
+
+	host = s
+	if strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]") {
+		host = s[1 : len(s)-1] // bracketed ipv6 without a port
+	} else if strings.HasPrefix(s, "[") || strings.Count(s, ":") == 1 {
+		var err error
+		if host, port, err = net.SplitHostPort(s); err != nil {
+			return "", "", "malformed host:port"
+		}
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			return "", "", "invalid port " + port
+		}
+	}
+
+	if ip, err := netip.ParseAddr(host); err == nil {
+		return ip.WithZone("").String(), port, "" // ip literal
+	}
+
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if len(host) == 0 {
+		return "", "", "empty host"
+	}
+	if !utf8.ValidString(host) {
+		return "", "", "invalid utf-8"
+	}
+	var err error
+	if host, err = lookup.ToASCII(host); err != nil {
+		return "", "", err.Error()
+	}
+	labels := strings.Split(host, ".")
+	for i := range labels {
+		if labels[i], reason = label(labels[i]); len(reason) > 0 {
+			return "", "", reason
+		}
+	}
+	if host = strings.Join(labels, "."); len(host) > 253 {
+		return "", "", "hostname too long"
+	}
+
+	return host, port, ""
+}
+
+// lookup is the UTS #46 mapping and IDNA validation of the hostnames;
+// underscores are accepted for service names such as _dmarc
+var lookup = idna.New(idna.MapForLookup(), idna.BidiRule(), idna.Transitional(false), idna.StrictDomainName(false))
+
+// label validates a hostname label in its ASCII form
+func label(s string) (string, string) {
+
+	if len(s) == 0 {
+		return "", "empty label"
+	}
+	if len(s) > 63 {
+		return "", "label too long"
+	}
+	if s[0] == '-' || s[len(s)-1] == '-' {
+		return "", "label " + s + " starts or ends with a hyphen"
+	}
+	for i := range len(s) {
+		if c := s[i]; !('a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '_') {
+			return "", "invalid character " + strconv.QuoteRune(rune(c)) + " in label " + s
+		}
+	}
+
+	return s, ""
+}
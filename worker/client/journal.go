@@ -0,0 +1,202 @@
+package client
+
+import (
+	"bufio"
+	"errors"
+	"hash/fnv"
+	"os"
+	"sync"
+	"time"
+)
+
+// Journal is the optional durable append-only log of the job.Request() keys
+// submitted to and completed by a Worker so that a long run can resume after
+// a crash or restart; a key is completed once the job is delivered on the
+// worker.Outbox and jobs abandoned by Shutdown remain unfinished
+//
+//	journal, err := client.OpenJournal("/var/lib/crawler/dns.journal")
+//	work := client.Worker{Path: "dns", Journal: journal}
+//	work.Connect(ctx)
+//	go func() {
+//		defer work.Done()
+//		for _, host := range journal.Pending() {
+//			work.Inbox <- job.NewDNS(host) // unfinished by the previous run
+//		}
+//		for host := range input {
+//			if !journal.Seen(host) {
+//				work.Inbox <- job.NewDNS(host)
+//			}
+//		}
+//	}()
+//	... range work.Outbox
+//	journal.Close()
+//
+// Records are buffered and written at least every second, so a crash may
+// repeat the most recent jobs but never skips an unfinished one
+type Journal struct {
+	mu      sync.Mutex
+	file    *os.File
+	w       *bufio.Writer
+	seen    map[uint64]struct{} // keys submitted by the previous runs
+	pending []string            // keys unfinished by the previous runs
+	keys    map[Job]string      // submitted keys of the jobs in progress
+	stop    chan struct{}       // stops the flusher
+	done    chan struct{}       // closed when the flusher exits
+}
+
+// journal record markers
+const (
+	submitted = '+'
+	completed = '-'
+)
+
+// OpenJournal opens or creates the journal file at path and loads the state
+// of the previous runs
+func OpenJournal(path string) (*Journal, error) {
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	j := &Journal{file: file, w: bufio.NewWriter(file), seen: make(map[uint64]struct{}),
+		keys: make(map[Job]string), stop: make(chan struct{}), done: make(chan struct{})}
+	if err = j.load(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	go j.flusher()
+
+	return j, nil
+}
+
+// hash is the journal key hash; the key state is tracked by hash so that
+// very large runs remain within memory
+func hash(key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return h.Sum64()
+}
+
+// load counts the unfinished submissions per key and then collects the
+// unfinished keys in their submission order
+func (j *Journal) load() error {
+
+	open := make(map[uint64]int)
+	scan := func(fn func(mark byte, key string)) error {
+		if _, err := j.file.Seek(0, 0); err != nil {
+			return err
+		}
+		s := bufio.NewScanner(j.file)
+		s.Buffer(nil, 1<<20)
+		for s.Scan() {
+			if line := s.Text(); len(line) > 1 {
+				fn(line[0], line[1:])
+			}
+		}
+		return s.Err()
+	}
+
+	if err := scan(func(mark byte, key string) {
+		h := hash(key)
+		switch mark {
+		case submitted:
+			j.seen[h] = struct{}{}
+			open[h]++
+		case completed:
+			open[h]--
+		}
+	}); err != nil {
+		return err
+	}
+
+	return scan(func(mark byte, key string) {
+		if h := hash(key); mark == submitted && open[h] > 0 {
+			open[h]--
+			j.pending = append(j.pending, key)
+		}
+	})
+}
+
+// Pending returns the keys submitted but not completed by the previous runs
+func (j *Journal) Pending() []string { return j.pending }
+
+// Seen reports whether key was submitted by a previous run, completed or
+// not; unfinished keys are returned by Pending
+func (j *Journal) Seen(key string) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	_, ok := j.seen[hash(key)]
+	return ok
+}
+
+// record appends the marked keys; a job is completed under the key it was
+// submitted with as the normalization or the response may change its key
+func (j *Journal) record(mark byte, jobs ...Job) {
+
+	if j == nil {
+		return
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for i := range jobs {
+		key := jobs[i].Request()
+		switch mark {
+		case submitted:
+			j.keys[jobs[i]] = key
+		case completed:
+			if k, ok := j.keys[jobs[i]]; ok {
+				key = k
+				delete(j.keys, jobs[i])
+			}
+		}
+		j.w.WriteByte(mark)
+		j.w.WriteString(key)
+		j.w.WriteByte('\n')
+	}
+}
+
+// flusher writes the buffered records every second
+func (j *Journal) flusher() {
+
+	defer close(j.done)
+	t := time.NewTicker(time.Second)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			j.Flush()
+		case <-j.stop:
+			return
+		}
+	}
+}
+
+// Flush writes the buffered records to the journal file
+func (j *Journal) Flush() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.w.Flush()
+}
+
+// Close flushes and closes the journal; the journal of a finished run can
+// be removed or Reset
+func (j *Journal) Close() error {
+	close(j.stop)
+	<-j.done
+	return errors.Join(j.Flush(), j.file.Close())
+}
+
+// Reset discards the journal records, such as after a completed run
+func (j *Journal) Reset() error {
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.w.Reset(j.file)
+	j.seen = make(map[uint64]struct{})
+	j.pending = nil
+
+	return j.file.Truncate(0)
+}
@@ -0,0 +1,192 @@
+package client
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Limiter is a token bucket request rate limiter shared by all of the
+// Worker goroutines and applied to every request attempt; a nil
+// Worker.Limiter is configured from the Worker.Pacer setting
+//
+// With AIMD enabled the rate backs off multiplicatively on 429 and 5xx
+// responses, transport failures, and latency growth, and ramps back up
+// additively on success but never beyond the Max requests per second
+//
+// Waiting requests are granted tokens by their Priority lane using the
+// Worker.Weights weighted fair scheduling
+type Limiter struct {
+	Rate     float64       // initial requests per second; default Max or 1/Worker.Pacer
+	Max      float64       // hard requests per second ceiling; default Rate
+	Min      float64       // AIMD requests per second floor; default 1
+	Burst    int           // token bucket size; default 1
+	AIMD     bool          // adapt the rate using server feedback
+	Increase float64       // AIMD additive increase in requests per second per second; default 1
+	Decrease float64       // AIMD multiplicative decrease factor; default 0.5
+	Latency  time.Duration // AIMD latency threshold; default twice the moving average
+
+	mu     sync.Mutex
+	rate   float64       // current requests per second
+	tokens float64       // available tokens
+	last   time.Time     // last token refill
+	cut    time.Time     // last AIMD decrease
+	avg    time.Duration // latency moving average
+
+	fair     *fair                  // waiter lane selector
+	waiters  [lanes][]chan struct{} // queued Wait calls per lane
+	granting bool                   // grant loop running
+}
+
+// configure applies the Limiter default settings from the pacer delay and
+// the lane weights
+func (l *Limiter) configure(pacer time.Duration, weights map[Priority]int) {
+
+	if l.Rate == 0 {
+		l.Rate = l.Max
+	}
+	if l.Rate == 0 {
+		l.Rate = float64(time.Second) / float64(pacer)
+	}
+	if l.Max == 0 {
+		l.Max = l.Rate
+	}
+	l.Rate = min(l.Rate, l.Max)
+	if l.Min == 0 {
+		l.Min = min(1, l.Rate)
+	}
+	if l.Burst == 0 {
+		l.Burst = 1
+	}
+	if l.Increase == 0 {
+		l.Increase = 1
+	}
+	if l.Decrease == 0 {
+		l.Decrease = 0.5
+	}
+
+	l.rate = l.Rate
+	l.tokens = float64(l.Burst)
+	l.last = time.Now()
+	l.fair = newFair(weights)
+}
+
+// Current returns the current requests per second rate
+func (l *Limiter) Current() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rate
+}
+
+// Wait blocks until a request token is available or ctx is done; waiting
+// calls are served by the ctx Priority lane, see WithPriority
+func (l *Limiter) Wait(ctx context.Context) error {
+
+	l.mu.Lock()
+	l.refill()
+	if l.tokens >= 1 && !l.queued() {
+		l.tokens--
+		l.mu.Unlock()
+		return nil
+	}
+	p := priority(ctx)
+	ready := make(chan struct{})
+	l.waiters[p] = append(l.waiters[p], ready)
+	if !l.granting {
+		l.granting = true
+		go l.grant()
+	}
+	l.mu.Unlock()
+
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if i := slices.Index(l.waiters[p], ready); i >= 0 {
+			l.waiters[p] = slices.Delete(l.waiters[p], i, i+1)
+		} else {
+			l.tokens++ // return the unused grant
+		}
+		return ctx.Err()
+	}
+}
+
+// refill adds the tokens accrued since the last refill
+func (l *Limiter) refill() {
+	now := time.Now()
+	l.tokens = min(float64(l.Burst), l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+}
+
+// queued reports whether any Wait call is queued
+func (l *Limiter) queued() bool {
+	for p := range lanes {
+		if len(l.waiters[p]) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// grant hands out the tokens to the queued Wait calls until none remain
+func (l *Limiter) grant() {
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for {
+		l.refill()
+		if !l.queued() {
+			l.granting = false
+			return
+		}
+		if l.tokens < 1 {
+			d := time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+			l.mu.Unlock()
+			time.Sleep(d)
+			l.mu.Lock()
+			continue
+		}
+		p, _ := l.fair.next(func(p Priority) bool { return len(l.waiters[p]) > 0 })
+		l.tokens--
+		close(l.waiters[p][0])
+		l.waiters[p] = l.waiters[p][1:]
+	}
+}
+
+// observe adapts the AIMD rate from the request outcome; status is the
+// http status code or 0 on transport failure
+func (l *Limiter) observe(status int, latency time.Duration) {
+
+	if !l.AIMD {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	threshold := l.Latency
+	if threshold == 0 && l.avg > 0 {
+		threshold = l.avg * 2
+	}
+	if l.avg == 0 {
+		l.avg = latency
+	} else {
+		l.avg += (latency - l.avg) / 8
+	}
+
+	switch {
+	case status == 0 || status == 429 || status >= 500 || threshold > 0 && latency > threshold:
+		// decrease at most once per second so that a burst of concurrent
+		// failures from a single overload event counts as one signal
+		if time.Since(l.cut) >= time.Second {
+			l.rate = max(l.Min, l.rate*l.Decrease)
+			l.cut = time.Now()
+		}
+	default:
+		l.rate = min(l.Max, l.rate+l.Increase/l.rate)
+	}
+}
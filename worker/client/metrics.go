@@ -0,0 +1,266 @@
+package client
+
+import (
+	"expvar"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Metrics receives the Worker instrumentation and can be bridged to any
+// metrics system; Registry is the built-in implementation with Prometheus
+// text format and expvar exposition
+//
+//	worker_requests_total{path,method,status}  counter; status 0 on transport failure
+//	worker_request_seconds{path,method}         histogram; http round trip latency
+//	worker_retries_total{path,method}           counter; retry attempts
+//	worker_batch_size{path}                     histogram; POST batch items per request
+//	worker_items_total{path,result}             counter; result ok|fail per job
+//	worker_unexpected_items_total{path}         counter; POST response items matching no job
+//	worker_pacer_wait_seconds{path}             histogram; Limiter wait time
+//	worker_inbox_depth{path}                    gauge; queued worker.Inbox and Priority lane jobs
+//	worker_outbox_depth{path}                   gauge; queued worker.Outbox jobs
+//	worker_cache_total{path,result}             counter; result hit|miss per Cache lookup
+//	worker_coalesced_total{path,method}         counter; jobs answered by an in-flight request
+//	worker_wire_bytes_total{path,direction}     counter; Compression sent|received bytes on the wire
+//	worker_body_bytes_total{path,direction}     counter; Compression sent|received decoded body bytes
+//	worker_secret_rotations_total{path}         counter; Secrets changes
+//	worker_secret_fallbacks_total{path}         counter; requests retried with the other rotation secret
+type Metrics interface {
+	Add(name string, labels map[string]string, delta float64)       // counter
+	Observe(name string, labels map[string]string, value float64)   // histogram
+	Gauge(name string, labels map[string]string, fn func() float64) // sampled gauge
+}
+
+// metric helpers are no-ops without worker.Metrics
+
+func (w *Worker) count(name string, delta float64, labels ...string) {
+	if w.Metrics != nil {
+		w.Metrics.Add(name, w.labels(labels), delta)
+	}
+}
+
+func (w *Worker) measure(name string, value float64, labels ...string) {
+	if w.Metrics != nil {
+		w.Metrics.Observe(name, w.labels(labels), value)
+	}
+}
+
+// labels returns the path label with the key, value label pairs
+func (w *Worker) labels(kv []string) map[string]string {
+	labels := map[string]string{"path": w.Path}
+	for i := 0; i+1 < len(kv); i += 2 {
+		labels[kv[i]] = kv[i+1]
+	}
+	return labels
+}
+
+// items counts the per job results
+func (w *Worker) items(jobs ...Job) {
+	if w.Metrics == nil {
+		return
+	}
+	for i := range jobs {
+		if jobs[i].Okay() {
+			w.count("worker_items_total", 1, "result", "ok")
+		} else {
+			w.count("worker_items_total", 1, "result", "fail")
+		}
+	}
+}
+
+// gauges registers the worker queue depth gauges
+func (w *Worker) gauges() {
+	if w.Metrics != nil {
+		w.Metrics.Gauge("worker_inbox_depth", w.labels(nil), func() float64 {
+			return float64(len(w.queue[Interactive]) + len(w.queue[Normal]) + len(w.queue[Bulk]))
+		})
+		w.Metrics.Gauge("worker_outbox_depth", w.labels(nil), func() float64 { return float64(len(w.Outbox)) })
+	}
+}
+
+var (
+	// default Registry histogram buckets
+	SecondsBuckets = []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+	SizeBuckets    = []float64{1, 2, 5, 10, 25, 50, 100, 250, 500, 1000}
+)
+
+// Registry is the in-memory Metrics implementation; histograms named with a
+// _seconds suffix use SecondsBuckets and all others use SizeBuckets unless
+// configured in Buckets
+type Registry struct {
+	Buckets map[string][]float64 // histogram buckets by metric name
+
+	mu      sync.Mutex
+	series  map[string]*series // by name and labels
+	gauges  map[string]func() float64
+	ordered []string // series keys in registration order
+}
+
+// series is a single counter or histogram time series
+type series struct {
+	name    string
+	labels  string    // rendered {k="v",...}
+	kind    string    // counter|histogram|gauge
+	value   float64   // counter total or histogram sum
+	count   uint64    // histogram observations
+	bounds  []float64 // histogram upper bounds
+	buckets []uint64  // histogram cumulative counts per bound
+}
+
+// key returns the series key and the rendered labels
+func key(name string, labels map[string]string) (string, string) {
+	var b strings.Builder
+	for i, k := range slices.Sorted(maps.Keys(labels)) {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(k + "=" + strconv.Quote(labels[k]))
+	}
+	if b.Len() == 0 {
+		return name, ""
+	}
+	return name + "{" + b.String() + "}", "{" + b.String() + "}"
+}
+
+// get returns the series for name and labels, creating it when required
+func (r *Registry) get(name, kind string, labels map[string]string) *series {
+
+	k, l := key(name, labels)
+	if r.series == nil {
+		r.series = make(map[string]*series)
+		r.gauges = make(map[string]func() float64)
+	}
+	s, ok := r.series[k]
+	if !ok {
+		s = &series{name: name, labels: l, kind: kind}
+		if kind == "histogram" {
+			s.bounds = r.Buckets[name]
+			if len(s.bounds) == 0 && strings.HasSuffix(name, "_seconds") {
+				s.bounds = SecondsBuckets
+			} else if len(s.bounds) == 0 {
+				s.bounds = SizeBuckets
+			}
+			s.buckets = make([]uint64, len(s.bounds))
+		}
+		r.series[k] = s
+		r.ordered = append(r.ordered, k)
+	}
+	return s
+}
+
+func (r *Registry) Add(name string, labels map[string]string, delta float64) {
+	r.mu.Lock()
+	r.get(name, "counter", labels).value += delta
+	r.mu.Unlock()
+}
+
+func (r *Registry) Observe(name string, labels map[string]string, value float64) {
+	r.mu.Lock()
+	s := r.get(name, "histogram", labels)
+	s.value += value
+	s.count++
+	for i := range s.bounds {
+		if value <= s.bounds[i] {
+			s.buckets[i]++
+		}
+	}
+	r.mu.Unlock()
+}
+
+func (r *Registry) Gauge(name string, labels map[string]string, fn func() float64) {
+	r.mu.Lock()
+	s := r.get(name, "gauge", labels)
+	r.gauges[s.name+s.labels] = fn
+	r.mu.Unlock()
+}
+
+// Value returns the current counter total, histogram sum, or gauge value
+func (r *Registry) Value(name string, labels map[string]string) float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	k, _ := key(name, labels)
+	if fn, ok := r.gauges[k]; ok {
+		return fn()
+	}
+	if s, ok := r.series[k]; ok {
+		return s.value
+	}
+	return 0
+}
+
+// WritePrometheus writes the metrics in the Prometheus text exposition format
+func (r *Registry) WritePrometheus(w io.Writer) error {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	keys := slices.Clone(r.ordered)
+	slices.SortStableFunc(keys, func(a, b string) int { return strings.Compare(r.series[a].name, r.series[b].name) })
+
+	var typed string
+	for _, k := range keys {
+		s := r.series[k]
+		if s.name != typed {
+			if _, err := fmt.Fprintf(w, "# TYPE %s %s\n", s.name, s.kind); err != nil {
+				return err
+			}
+			typed = s.name
+		}
+		switch s.kind {
+		case "counter":
+			fmt.Fprintf(w, "%s%s %v\n", s.name, s.labels, s.value)
+		case "gauge":
+			fmt.Fprintf(w, "%s%s %v\n", s.name, s.labels, r.gauges[k]())
+		case "histogram":
+			for i := range s.bounds {
+				fmt.Fprintf(w, "%s_bucket%s %d\n", s.name, le(s.labels, strconv.FormatFloat(s.bounds[i], 'g', -1, 64)), s.buckets[i])
+			}
+			fmt.Fprintf(w, "%s_bucket%s %d\n", s.name, le(s.labels, "+Inf"), s.count)
+			fmt.Fprintf(w, "%s_sum%s %v\n", s.name, s.labels, s.value)
+			fmt.Fprintf(w, "%s_count%s %d\n", s.name, s.labels, s.count)
+		}
+	}
+
+	return nil
+}
+
+// le adds the histogram le label to the rendered labels
+func le(labels, bound string) string {
+	if len(labels) == 0 {
+		return `{le="` + bound + `"}`
+	}
+	return labels[:len(labels)-1] + `,le="` + bound + `"}`
+}
+
+// ServeHTTP serves the Prometheus text format for scraping
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	r.WritePrometheus(w)
+}
+
+// Expvar returns the metrics as an expvar.Var for expvar.Publish; counters
+// and gauges map to their value and histograms to their count and sum
+func (r *Registry) Expvar() expvar.Var {
+	return expvar.Func(func() any {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		m := make(map[string]any, len(r.series))
+		for k, s := range r.series {
+			switch s.kind {
+			case "counter":
+				m[k] = s.value
+			case "gauge":
+				m[k] = r.gauges[k]()
+			case "histogram":
+				m[k] = map[string]any{"count": s.count, "sum": s.value}
+			}
+		}
+		return m
+	})
+}
@@ -0,0 +1,56 @@
+package client
+
+import (
+	"net/http"
+	"time"
+)
+
+// RoundTrip performs a single worker http request
+type RoundTrip func(*http.Request) (*http.Response, error)
+
+// Middleware wraps every worker GET, POST, and health probe request; a
+// middleware may mutate the request, observe or replace the response, or
+// short-circuit the call entirely, such as for caching or fault injection
+//
+// The Worker chain is the worker.AuthHeader followed by worker.Middleware
+// in order, so the first Middleware sees the authenticated request and the
+// last Middleware calls the worker.Client
+type Middleware func(next RoundTrip) RoundTrip
+
+// Mutate returns a Middleware that applies fn to every request
+func Mutate(fn func(*http.Request)) Middleware {
+	return func(next RoundTrip) RoundTrip {
+		return func(req *http.Request) (*http.Response, error) {
+			fn(req)
+			return next(req)
+		}
+	}
+}
+
+// Observe returns a Middleware that reports every request outcome and
+// latency to fn; resp is nil when err is not
+func Observe(fn func(req *http.Request, resp *http.Response, err error, latency time.Duration)) Middleware {
+	return func(next RoundTrip) RoundTrip {
+		return func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next(req)
+			fn(req, resp, err, time.Since(start))
+			return resp, err
+		}
+	}
+}
+
+// chain composes the worker.AuthHeader, or the worker.Secrets, and
+// worker.Middleware around the worker.Client
+func (w *Worker) chain() RoundTrip {
+
+	rt := RoundTrip(w.Client.Do)
+	for i := len(w.Middleware) - 1; i >= 0; i-- {
+		rt = w.Middleware[i](rt)
+	}
+
+	if w.Secrets != nil {
+		return w.authenticate(rt)
+	}
+	return Mutate(w.AuthHeader)(rt)
+}
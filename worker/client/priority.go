@@ -0,0 +1,88 @@
+package client
+
+import "context"
+
+// Priority is the Worker scheduling lane of a job; jobs are taken from the
+// lanes and granted Limiter tokens using weighted fair scheduling so that
+// interactive lookups are not queued behind a bulk backfill while the bulk
+// lane is never starved
+//
+//	work.Submit(client.WithPriority(ctx, client.Interactive), job.NewDNS(host))
+//	work.Do(client.WithPriority(ctx, client.Interactive), job.NewDNS(host))
+//
+// Jobs sent directly on the worker.Inbox use the Normal lane
+type Priority int
+
+const (
+	Normal      Priority = iota // default lane; the worker.Inbox
+	Interactive                 // latency sensitive lookups
+	Bulk                        // background backfill
+	lanes                       // number of lanes
+)
+
+// DefaultWeights is the default fair share per Priority lane
+var DefaultWeights = map[Priority]int{Interactive: 8, Normal: 4, Bulk: 1}
+
+// order is the lane service order within a scheduling round
+var order = [lanes]Priority{Interactive, Normal, Bulk}
+
+func (p Priority) String() string {
+	switch p {
+	case Interactive:
+		return "interactive"
+	case Bulk:
+		return "bulk"
+	}
+	return "normal"
+}
+
+type priorityKey struct{}
+
+// WithPriority returns a context that submits or performs jobs on the
+// Priority lane; see Worker.Submit and Worker.Do
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+// priority returns the ctx Priority lane
+func priority(ctx context.Context) Priority {
+	if p, ok := ctx.Value(priorityKey{}).(Priority); ok && p >= 0 && p < lanes {
+		return p
+	}
+	return Normal
+}
+
+// fair is the weighted round robin lane selector
+type fair struct {
+	weights [lanes]int
+	credit  [lanes]int
+}
+
+// newFair returns the lane selector for the weights; lanes without a
+// positive weight use the DefaultWeights
+func newFair(weights map[Priority]int) *fair {
+	f := new(fair)
+	for p := range lanes {
+		if f.weights[p] = weights[p]; f.weights[p] <= 0 {
+			f.weights[p] = DefaultWeights[p]
+		}
+	}
+	return f
+}
+
+// next selects the ready lane with scheduling credit remaining in the
+// round, starting a new round when required; false when no lane is ready
+func (f *fair) next(ready func(Priority) bool) (Priority, bool) {
+
+	for range 2 {
+		for _, p := range order {
+			if f.credit[p] > 0 && ready(p) {
+				f.credit[p]--
+				return p, true
+			}
+		}
+		f.credit = f.weights
+	}
+
+	return Normal, false
+}
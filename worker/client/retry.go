@@ -0,0 +1,119 @@
+package client
+
+import (
+	"context"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// Retry policy configuration for the Worker; a nil Worker.Retry makes a
+// single attempt per GET request or POST batch
+//
+// Transport failures, timeouts, and the retryable Status codes are retried
+// using exponential backoff with jitter; a server Retry-After header
+// overrides the computed backoff when it is longer
+type Retry struct {
+	Attempts int           `json:"attempts,omitempty"` // max attempts including the first; default 3
+	Base     time.Duration `json:"base,omitempty"`     // initial backoff; default 100ms
+	Max      time.Duration `json:"max,omitempty"`      // backoff ceiling; default 5s
+	Jitter   float64       `json:"jitter,omitempty"`   // backoff randomization fraction 0..1; default 0.2
+	Status   []int         `json:"status,omitempty"`   // retryable http status codes; default 429,502,503,504
+	Items    bool          `json:"items,omitempty"`    // POST: resubmit only the items reporting !Okay() in a successful batch
+}
+
+// configure applies the Retry default settings
+func (r *Retry) configure() {
+	if r.Attempts == 0 {
+		r.Attempts = 3
+	}
+	if r.Base == 0 {
+		r.Base = time.Millisecond * 100
+	}
+	if r.Max == 0 {
+		r.Max = time.Second * 5
+	}
+	if r.Jitter == 0 {
+		r.Jitter = 0.2
+	}
+	if len(r.Status) == 0 {
+		r.Status = []int{
+			http.StatusTooManyRequests,    // 429
+			http.StatusBadGateway,         // 502
+			http.StatusServiceUnavailable, // 503
+			http.StatusGatewayTimeout,     // 504
+		}
+	}
+}
+
+// retryable reports whether err is a transient failure
+func (r *Retry) retryable(err *Error) bool {
+	switch {
+	case err.StatusCode == 0:
+		return err.Err != nil // transport failure or timeout
+	case err.Err != nil:
+		return false // decode failure
+	}
+	return slices.Contains(r.Status, err.StatusCode)
+}
+
+// backoff returns the delay before the next attempt; attempt is zero based
+func (r *Retry) backoff(attempt int, retryAfter time.Duration) time.Duration {
+
+	d := r.Base << attempt
+	if d <= 0 || d > r.Max {
+		d = r.Max
+	}
+	if r.Jitter > 0 {
+		d -= time.Duration(rand.Float64() * r.Jitter * float64(d))
+	}
+	if retryAfter > d {
+		d = retryAfter
+	}
+
+	return d
+}
+
+// wait reports whether another attempt should be made after a failed
+// attempt (zero based) and sleeps for the backoff period; a nil err signals
+// an item level retry in a successful batch
+func (r *Retry) wait(ctx context.Context, attempt int, err *Error) bool {
+
+	if r == nil || attempt+1 >= r.Attempts || err != nil && !r.retryable(err) {
+		return false
+	}
+
+	var retryAfter time.Duration
+	if err != nil {
+		retryAfter = err.RetryAfter
+	}
+
+	timer := time.NewTimer(r.backoff(attempt, retryAfter))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// retryAfter parses the Retry-After header in either the delay-seconds
+// or the http-date form; zero when not present or invalid
+func retryAfter(h http.Header) time.Duration {
+
+	v := h.Get("Retry-After")
+	if len(v) == 0 {
+		return 0
+	}
+	if n, err := strconv.Atoi(v); err == nil && n > 0 {
+		return time.Duration(n) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(time.Until(t), 0)
+	}
+
+	return 0
+}
@@ -0,0 +1,260 @@
+package client
+
+import (
+	"context"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zxdev/passkey"
+)
+
+// SecretSource provides the current worker passkey secret; SecretFile,
+// SecretEnv, and SecretExec are built-in while a vault or secret manager is
+// plugged in by implementing SecretSource
+type SecretSource interface {
+	Secret(ctx context.Context) (string, error)
+}
+
+// SecretFile reads the secret from the file at Path, such as a mounted
+// kubernetes secret, which is reloaded when it changes
+type SecretFile struct {
+	Path string
+}
+
+func (s SecretFile) Secret(context.Context) (string, error) {
+	b, err := os.ReadFile(s.Path)
+	return strings.TrimSpace(string(b)), err
+}
+
+// SecretEnv reads the secret from the Name environment variable
+type SecretEnv struct {
+	Name string
+}
+
+func (s SecretEnv) Secret(context.Context) (string, error) {
+	v, ok := os.LookupEnv(s.Name)
+	if !ok {
+		return "", fmt.Errorf("secret: %s is not set", s.Name)
+	}
+	return strings.TrimSpace(v), nil
+}
+
+// SecretExec runs the Command and reads the secret from its output, such as
+// from a password manager or cloud secret cli
+type SecretExec struct {
+	Command []string      // command and arguments
+	Timeout time.Duration // command timeout; default 10s
+}
+
+func (s SecretExec) Secret(ctx context.Context) (string, error) {
+
+	if len(s.Command) == 0 {
+		return "", errors.New("secret: no command")
+	}
+	if s.Timeout == 0 {
+		s.Timeout = time.Second * 10
+	}
+	ctx, cancel := context.WithTimeout(ctx, s.Timeout)
+	defer cancel()
+
+	b, err := exec.CommandContext(ctx, s.Command[0], s.Command[1:]...).Output()
+	if err != nil {
+		return "", fmt.Errorf("secret: %s: %w", s.Command[0], err)
+	}
+
+	return strings.TrimSpace(string(b)), nil
+}
+
+// Secrets configuration for the Worker passkey authentication; the Source
+// is polled every Refresh and a changed secret is used immediately, while
+// during the rotation Window the requests the cluster rejects with 401 or
+// 400 are retried with the previous secret so that the client and cluster
+// secrets can be rotated in either order without a restart
+//
+// The worker.Secret is used until the Source first succeeds and the
+// secret changes are counted as worker_secret_rotations_total and the
+// previous secret fallbacks as worker_secret_fallbacks_total
+type Secrets struct {
+	Source  SecretSource  // secret source
+	Refresh time.Duration // source poll interval; default 30s
+	Window  time.Duration // previous secret fallback window; default 10m
+
+	once     sync.Once
+	mu       sync.RWMutex
+	secret   string          // current secret
+	current  *passkey.Client // current secret client
+	previous *passkey.Client // previous secret client during the Window
+	rotated  time.Time       // current secret start
+	prefer   bool            // try the previous secret first
+	cancel   [2]func()       // current and previous passkey generators
+	err      error           // last Source failure
+}
+
+// Err returns the last Source failure or nil when the last poll succeeded
+func (s *Secrets) Err() error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.err
+}
+
+// configure applies the Secrets default settings, loads the initial secret,
+// and starts polling the Source until ctx is done; Secrets shared by several
+// workers are configured by the first one
+func (s *Secrets) configure(ctx context.Context, w *Worker) {
+	s.once.Do(func() { s.start(ctx, w) })
+}
+
+// start loads the initial secret and polls the Source
+func (s *Secrets) start(ctx context.Context, w *Worker) {
+
+	if s.Refresh == 0 {
+		s.Refresh = time.Second * 30
+	}
+	if s.Window == 0 {
+		s.Window = time.Minute * 10
+	}
+
+	if len(w.Secret) > 0 && valid(w.Secret) == nil {
+		s.rotate(ctx, w, w.Secret)
+	}
+	s.load(ctx, w)
+
+	go func() {
+		ticker := time.NewTicker(s.Refresh)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.load(ctx, w)
+			}
+		}
+	}()
+}
+
+// load reads the Source and rotates to a changed secret
+func (s *Secrets) load(ctx context.Context, w *Worker) {
+
+	secret, err := s.Source.Secret(ctx)
+	if err == nil {
+		err = valid(secret)
+	}
+
+	s.mu.Lock()
+	s.err = err
+	changed := err == nil && secret != s.secret
+	s.mu.Unlock()
+
+	if changed {
+		s.rotate(ctx, w, secret)
+	}
+}
+
+// rotate makes secret current and keeps the current secret as the previous
+// one for the Window
+func (s *Secrets) rotate(ctx context.Context, w *Worker, secret string) {
+
+	ctx, cancel := context.WithCancel(ctx)
+	client := passkey.NewClient(ctx, secret)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cancel[1] != nil {
+		s.cancel[1]()
+	}
+	if s.current != nil {
+		w.count("worker_secret_rotations_total", 1)
+	}
+	s.previous, s.cancel[1] = s.current, s.cancel[0]
+	s.current, s.cancel[0] = client, cancel
+	s.secret, s.rotated, s.prefer = secret, time.Now(), false
+}
+
+// clients returns the passkey clients to try in order
+func (s *Secrets) clients() []*passkey.Client {
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	switch {
+	case s.current == nil:
+		return nil
+	case s.previous == nil || time.Since(s.rotated) > s.Window:
+		return []*passkey.Client{s.current}
+	case s.prefer:
+		return []*passkey.Client{s.previous, s.current}
+	}
+	return []*passkey.Client{s.current, s.previous}
+}
+
+// authenticate is the Middleware that sets the passkey header from the
+// worker.Secrets and falls back to the other secret during the Window; a
+// request body that cannot be replayed is not retried but the other secret
+// is tried first by the following requests
+func (w *Worker) authenticate(next RoundTrip) RoundTrip {
+
+	s := w.Secrets
+	rejected := func(resp *http.Response) bool {
+		return resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusBadRequest
+	}
+
+	return func(req *http.Request) (*http.Response, error) {
+
+		clients := s.clients()
+		if len(clients) == 0 {
+			return next(req) // no valid secret; rejected by the cluster
+		}
+
+		clients[0].SetHeader(req)
+		resp, err := next(req)
+		if err != nil || !rejected(resp) || len(clients) == 1 {
+			return resp, err
+		}
+
+		// rejected during the rotation window
+		s.flip()
+		if req.Body != nil {
+			if req.GetBody == nil {
+				return resp, nil
+			}
+			body, err := req.GetBody()
+			if err != nil {
+				return resp, nil
+			}
+			req.Body = body
+		}
+		resp.Body.Close()
+
+		w.count("worker_secret_fallbacks_total", 1)
+		clients[1].SetHeader(req)
+		if resp, err = next(req); err == nil && rejected(resp) {
+			s.flip() // neither secret was accepted
+		}
+
+		return resp, err
+	}
+}
+
+// flip swaps the secret tried first during the Window
+func (s *Secrets) flip() {
+	s.mu.Lock()
+	s.prefer = !s.prefer
+	s.mu.Unlock()
+}
+
+// valid reports whether secret is a 32 character base32 passkey secret
+func valid(secret string) error {
+	if b, err := base32.StdEncoding.DecodeString(secret); len(secret) != 32 || err != nil || len(b) != 20 {
+		return errors.New("secret: not a 32 character base32 passkey secret")
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package client
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrClosed is returned by Submit after the Worker stopped accepting jobs
+var ErrClosed = errors.New("worker: closed")
+
+// Submit sends job on the worker.Inbox, or the ctx Priority lane, and is safe
+// to call concurrently with and after Done or Shutdown, when it returns
+// ErrClosed instead of panicking; see WithPriority
+func (w *Worker) Submit(ctx context.Context, job Job) error {
+
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if w.closed {
+		return ErrClosed
+	}
+	select {
+	case w.queue[priority(ctx)] <- job:
+		return nil
+	case <-w.quit:
+		return ErrClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Shutdown stops accepting jobs and drains the queued and in-flight jobs to
+// the worker.Outbox; when ctx expires first the in-flight requests are
+// canceled and ctx.Err() is returned. The jobs that were not delivered on
+// the worker.Outbox are returned in either case; canceled jobs are reset so
+// they can be resubmitted. Shutdown is safe to call multiple times and from
+// a ctx.Done() watcher; every call waits for the drain to finish.
+func (w *Worker) Shutdown(ctx context.Context) (Jobs, error) {
+
+	if w.quit == nil {
+		return nil, nil // not connected
+	}
+
+	w.once.Do(func() {
+		close(w.quit) // release blocked Submit calls
+		w.mu.Lock()
+		w.closed = true
+		for p := range lanes {
+			close(w.queue[p])
+		}
+		w.mu.Unlock()
+	})
+
+	var err error
+	select {
+	case <-w.finished:
+	case <-ctx.Done():
+		err = ctx.Err()
+		w.aborted.Do(func() { close(w.abort) })
+		w.cancel()
+		<-w.finished
+	}
+
+	w.lostMu.Lock()
+	defer w.lostMu.Unlock()
+	return append(Jobs(nil), w.lost...), err
+}
+
+// Done shuts down the channels and cleanly exits once every queued job
+// has been processed; see Shutdown
+func (w *Worker) Done() { w.Shutdown(context.Background()) }
+
+// finish closes the worker.Outbox once every worker goroutine has exited
+func (w *Worker) finish() {
+	w.jobs.Wait()
+	close(w.Outbox)
+	w.stop()
+	w.cancel()
+	close(w.finished)
+}
+
+// emit delivers the job on the worker.Outbox or records it as unprocessed
+// when the worker context is done
+func (w *Worker) emit(ctx context.Context, job Job) {
+	if ctx.Err() == nil {
+		select {
+		case w.Outbox <- job:
+			w.Journal.record(completed, job)
+			return
+		case <-ctx.Done():
+		}
+	}
+	w.abandon(job)
+}
+
+// abandon records an undelivered job; a job whose request was canceled
+// is reset so that it can be resubmitted
+func (w *Worker) abandon(job Job) {
+	if err := job.Err(); errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		job.Fail(0, nil)
+	}
+	w.lostMu.Lock()
+	w.lost = append(w.lost, job)
+	w.lostMu.Unlock()
+}
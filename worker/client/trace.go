@@ -0,0 +1,345 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Tracer starts the Worker spans; a span is opened per GET job or POST batch
+// with child events per job keyed by UUID and Request(), and a child span per
+// http attempt whose W3C traceparent header is propagated to the cluster.
+// Recorder is the built-in OpenTelemetry compatible implementation.
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is a single traced operation
+type Span interface {
+	SetAttr(key, value string)       // span attribute
+	Event(name string, kv ...string) // timestamped event with key, value attribute pairs
+	End(err error)                   // complete the span with the failure, if any
+	TraceParent() string             // W3C traceparent header value
+}
+
+// nopSpan is used without a worker.Tracer
+type nopSpan struct{}
+
+func (nopSpan) SetAttr(string, string)  {}
+func (nopSpan) Event(string, ...string) {}
+func (nopSpan) End(error)               {}
+func (nopSpan) TraceParent() string     { return "" }
+
+// trace starts a span using the worker.Tracer
+func (w *Worker) trace(ctx context.Context, name string) (context.Context, Span) {
+	if w.Tracer == nil {
+		return ctx, nopSpan{}
+	}
+	ctx, span := w.Tracer.Start(ctx, name)
+	span.SetAttr("worker.path", w.Path)
+	return ctx, span
+}
+
+// traceJobs adds a job event per job to the span
+func traceJobs(span Span, event string, jobs ...Job) {
+	if _, ok := span.(nopSpan); ok {
+		return
+	}
+	for i := range jobs {
+		kv := []string{"job.request", jobs[i].Request(), "job.okay", strconv.FormatBool(jobs[i].Okay())}
+		if t, ok := jobs[i].(Tracker); ok {
+			kv = append(kv, "job.uuid", strconv.FormatUint(t.ID(), 10))
+		}
+		if err := jobs[i].Err(); err != nil {
+			kv = append(kv, "job.error", err.Error())
+		}
+		span.Event(event, kv...)
+	}
+}
+
+// SpanData is a completed span
+type SpanData struct {
+	TraceID  string            `json:"trace_id"`
+	SpanID   string            `json:"span_id"`
+	ParentID string            `json:"parent_id,omitempty"`
+	Name     string            `json:"name"`
+	Start    time.Time         `json:"start"`
+	End      time.Time         `json:"end"`
+	Attrs    map[string]string `json:"attrs,omitempty"`
+	Events   []SpanEvent       `json:"events,omitempty"`
+	Error    string            `json:"error,omitempty"`
+}
+
+// SpanEvent is a timestamped span event
+type SpanEvent struct {
+	Name  string            `json:"name"`
+	Time  time.Time         `json:"time"`
+	Attrs map[string]string `json:"attrs,omitempty"`
+}
+
+// Recorder is the in-memory Tracer; completed spans are kept for Spans
+// and passed to Export, such as OTLP.Export, when configured
+type Recorder struct {
+	Export func(SpanData) // completed span exporter; optional
+	Keep   int            // completed spans kept for Spans; default 1024
+
+	mu    sync.Mutex
+	spans []SpanData
+}
+
+type spanKey struct{}
+
+// span is the Recorder Span
+type span struct {
+	rec  *Recorder
+	mu   sync.Mutex
+	data SpanData
+}
+
+func (r *Recorder) Start(ctx context.Context, name string) (context.Context, Span) {
+
+	s := &span{rec: r, data: SpanData{Name: name, Start: time.Now(), SpanID: id(8)}}
+	if parent, ok := ctx.Value(spanKey{}).(*span); ok {
+		s.data.TraceID, s.data.ParentID = parent.data.TraceID, parent.data.SpanID
+	} else {
+		s.data.TraceID = id(16)
+	}
+
+	return context.WithValue(ctx, spanKey{}, s), s
+}
+
+// Spans returns the completed spans
+func (r *Recorder) Spans() []SpanData {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]SpanData(nil), r.spans...)
+}
+
+func (s *span) SetAttr(key, value string) {
+	s.mu.Lock()
+	if s.data.Attrs == nil {
+		s.data.Attrs = make(map[string]string)
+	}
+	s.data.Attrs[key] = value
+	s.mu.Unlock()
+}
+
+func (s *span) Event(name string, kv ...string) {
+	e := SpanEvent{Name: name, Time: time.Now()}
+	if len(kv) > 1 {
+		e.Attrs = make(map[string]string, len(kv)/2)
+		for i := 0; i+1 < len(kv); i += 2 {
+			e.Attrs[kv[i]] = kv[i+1]
+		}
+	}
+	s.mu.Lock()
+	s.data.Events = append(s.data.Events, e)
+	s.mu.Unlock()
+}
+
+func (s *span) End(err error) {
+
+	s.mu.Lock()
+	s.data.End = time.Now()
+	if err != nil {
+		s.data.Error = err.Error()
+	}
+	data := s.data
+	s.mu.Unlock()
+
+	r := s.rec
+	keep := r.Keep
+	if keep == 0 {
+		keep = 1024
+	}
+	r.mu.Lock()
+	if r.spans = append(r.spans, data); len(r.spans) > keep {
+		r.spans = r.spans[len(r.spans)-keep:]
+	}
+	r.mu.Unlock()
+
+	if r.Export != nil {
+		r.Export(data)
+	}
+}
+
+func (s *span) TraceParent() string {
+	return "00-" + s.data.TraceID + "-" + s.data.SpanID + "-01"
+}
+
+// id returns a random hex id of n bytes
+func id(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// OTLP exports completed spans to an OpenTelemetry collector using the
+// OTLP/HTTP json protocol; spans are sent in the background in batches of
+// Batch, and the remaining spans on Flush, which also reports the failed
+// background sends
+//
+//	otlp := &client.OTLP{Endpoint: "http://localhost:4318/v1/traces", Service: "crawler"}
+//	tracer := &client.Recorder{Export: otlp.Export}
+type OTLP struct {
+	Endpoint string       // collector traces endpoint; default http://localhost:4318/v1/traces
+	Service  string       // service.name resource attribute; default worker
+	Batch    int          // spans per export request; default 64
+	Client   *http.Client // default timeout 5-second
+
+	once    sync.Once
+	mu      sync.Mutex
+	idle    sync.Cond // signaled when a background send completes
+	pending []SpanData
+	sending int   // background sends in flight
+	err     error // background send failures since the last Flush
+}
+
+// configure applies the OTLP default settings
+func (o *OTLP) configure() {
+	if len(o.Endpoint) == 0 {
+		o.Endpoint = "http://localhost:4318/v1/traces"
+	}
+	if len(o.Service) == 0 {
+		o.Service = "worker"
+	}
+	if o.Batch == 0 {
+		o.Batch = 64
+	}
+	if o.Client == nil {
+		o.Client = &http.Client{Timeout: time.Second * 5}
+	}
+	o.idle.L = &o.mu
+}
+
+// Export queues the span and sends a full batch in the background
+func (o *OTLP) Export(data SpanData) {
+
+	o.once.Do(o.configure)
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.pending = append(o.pending, data); len(o.pending) < o.Batch {
+		return
+	}
+	batch := o.pending
+	o.pending = nil
+	o.sending++
+
+	go func() {
+		err := o.send(context.Background(), batch)
+		o.mu.Lock()
+		o.err = errors.Join(o.err, err)
+		o.sending--
+		o.idle.Broadcast()
+		o.mu.Unlock()
+	}()
+}
+
+// Flush waits for the background sends and sends the queued spans
+func (o *OTLP) Flush(ctx context.Context) error {
+
+	o.once.Do(o.configure)
+	o.mu.Lock()
+	for o.sending > 0 {
+		o.idle.Wait()
+	}
+	batch, err := o.pending, o.err
+	o.pending, o.err = nil, nil
+	o.mu.Unlock()
+
+	if len(batch) > 0 {
+		err = errors.Join(err, o.send(ctx, batch))
+	}
+
+	return err
+}
+
+// send posts the OTLP json encoded spans to the collector
+func (o *OTLP) send(ctx context.Context, batch []SpanData) error {
+
+	type kv struct {
+		Key   string `json:"key"`
+		Value struct {
+			StringValue string `json:"stringValue"`
+		} `json:"value"`
+	}
+	attrs := func(m map[string]string) (a []kv) {
+		for k, v := range m {
+			var item kv
+			item.Key, item.Value.StringValue = k, v
+			a = append(a, item)
+		}
+		return
+	}
+	type event struct {
+		Name       string `json:"name"`
+		TimeUnix   string `json:"timeUnixNano"`
+		Attributes []kv   `json:"attributes,omitempty"`
+	}
+	type status struct {
+		Code    int    `json:"code"` // 1 ok, 2 error
+		Message string `json:"message,omitempty"`
+	}
+	type otlpSpan struct {
+		TraceID      string  `json:"traceId"`
+		SpanID       string  `json:"spanId"`
+		ParentSpanID string  `json:"parentSpanId,omitempty"`
+		Name         string  `json:"name"`
+		Kind         int     `json:"kind"` // 3 client
+		Start        string  `json:"startTimeUnixNano"`
+		End          string  `json:"endTimeUnixNano"`
+		Attributes   []kv    `json:"attributes,omitempty"`
+		Events       []event `json:"events,omitempty"`
+		Status       status  `json:"status"`
+	}
+
+	spans := make([]otlpSpan, len(batch))
+	for i, d := range batch {
+		s := otlpSpan{TraceID: d.TraceID, SpanID: d.SpanID, ParentSpanID: d.ParentID, Name: d.Name, Kind: 3,
+			Start:      strconv.FormatInt(d.Start.UnixNano(), 10),
+			End:        strconv.FormatInt(d.End.UnixNano(), 10),
+			Attributes: attrs(d.Attrs),
+			Status:     status{Code: 1},
+		}
+		if len(d.Error) > 0 {
+			s.Status = status{Code: 2, Message: d.Error}
+		}
+		for _, e := range d.Events {
+			s.Events = append(s.Events, event{Name: e.Name, TimeUnix: strconv.FormatInt(e.Time.UnixNano(), 10), Attributes: attrs(e.Attrs)})
+		}
+		spans[i] = s
+	}
+
+	payload := map[string]any{"resourceSpans": []any{map[string]any{
+		"resource":   map[string]any{"attributes": attrs(map[string]string{"service.name": o.Service})},
+		"scopeSpans": []any{map[string]any{"scope": map[string]string{"name": "github.com/zxdev/client/worker/client"}, "spans": spans}},
+	}}}
+
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", o.Endpoint, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := o.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return &Error{Method: "POST", URL: o.Endpoint, StatusCode: resp.StatusCode}
+	}
+
+	return nil
+}
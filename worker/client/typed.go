@@ -0,0 +1,113 @@
+package client
+
+import (
+	"context"
+	"sync"
+)
+
+// Typed is the generic Worker for a single concrete job type so that the
+// Inbox and Outbox channels carry T directly and results do not require
+// Unpack type assertions; configure the embedded Worker as usual
+//
+//	var work = client.Typed[*job.DNS]{Worker: client.Worker{Path: "dns"}}
+//	work.Connect(ctx)
+//	for r := range work.Outbox {
+//		if r.Okay() {
+//			fmt.Println(r.Host, r.A)
+//		}
+//	}
+type Typed[T Job] struct {
+	Worker                      // worker configuration
+	Inbox, Outbox chan T        // typed worker communication channels
+	once          sync.Once     // single typed Inbox close
+	fed           chan struct{} // closed when the typed Inbox is relayed
+	relayed       chan struct{} // closed when the typed Outbox is closed
+	lost          []T           // jobs not accepted by the Worker
+}
+
+// Connect configures and connects the embedded Worker and starts relaying
+// the typed channels; see Worker.Connect
+func (t *Typed[T]) Connect(ctx context.Context) *Typed[T] {
+
+	t.Worker.Connect(ctx)
+	t.Inbox = make(chan T, cap(t.Worker.Inbox))
+	t.Outbox = make(chan T, cap(t.Worker.Outbox))
+	t.fed = make(chan struct{})
+	t.relayed = make(chan struct{})
+
+	go func() {
+		defer close(t.fed)
+		for job := range t.Inbox {
+			if t.Worker.Submit(ctx, job) != nil {
+				t.lost = append(t.lost, job)
+			}
+		}
+	}()
+
+	go func() {
+		defer close(t.relayed)
+		defer close(t.Outbox)
+		for job := range t.Worker.Outbox {
+			select {
+			case t.Outbox <- job.(T): // only T is ever submitted
+			case <-t.Worker.abort:
+				t.Worker.abandon(job)
+			case <-ctx.Done():
+				t.Worker.abandon(job)
+			}
+		}
+	}()
+
+	return t
+}
+
+// Shutdown is the typed Worker.Shutdown
+func (t *Typed[T]) Shutdown(ctx context.Context) ([]T, error) {
+
+	t.once.Do(func() { close(t.Inbox) })
+	select {
+	case <-t.fed:
+	case <-ctx.Done():
+	}
+	lost, err := t.Worker.Shutdown(ctx)
+	<-t.fed
+	if err != nil {
+		// the canceled relay abandons the undelivered jobs
+		<-t.relayed
+		lost, _ = t.Worker.Shutdown(ctx)
+	}
+
+	jobs := append([]T(nil), t.lost...)
+	for i := range lost {
+		jobs = append(jobs, lost[i].(T))
+	}
+
+	return jobs, err
+}
+
+// Done shuts down the typed channels and cleanly exits; see Worker.Done
+func (t *Typed[T]) Done() { t.Shutdown(context.Background()) }
+
+// Do is the typed Worker.Do
+func (t *Typed[T]) Do(ctx context.Context, job T) (T, error) {
+	_, err := t.Worker.Do(ctx, job)
+	return job, err
+}
+
+// DoBatch is the typed Worker.DoBatch
+func (t *Typed[T]) DoBatch(ctx context.Context, jobs []T) (map[string]T, error) {
+
+	batch := make(Jobs, len(jobs))
+	keys := make([]string, len(jobs))
+	for i := range jobs {
+		batch[i], keys[i] = jobs[i], jobs[i].Request()
+	}
+	_, err := t.Worker.DoBatch(ctx, batch)
+
+	result := make(map[string]T, len(jobs))
+	for i := range jobs {
+		result[keys[i]] = jobs[i]
+	}
+
+	return result, err
+}
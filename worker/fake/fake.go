@@ -0,0 +1,276 @@
+// Package fake is an in-process worker cluster node for hermetic tests of
+// client.Worker code; it implements the GET /{path}/{host} and POST /{path}
+// protocols for every job type with passkey authentication, fixture driven
+// or programmable responses, and latency, failure, reordering, and partial
+// batch injection
+//
+//	srv := (&fake.Server{Secret: secret, Latency: time.Millisecond}).Start(t.Context())
+//	defer srv.Close()
+//	work := client.Worker{Host: srv.URL, Secret: secret, Path: "dns"}
+package fake
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/zxdev/passkey"
+)
+
+// Keys is the request key field echoed in the response items by path;
+// every job type is served
+var Keys = map[string]string{
+	"title":    "url",
+	"method":   "url",
+	"hval":     "item",
+	"dns":      "host",
+	"rdap":     "host",
+	"cert":     "host",
+	"mail":     "host",
+	"crtsh":    "host",
+	"firewall": "host",
+}
+
+// Server is the fake worker cluster node configuration; the response item
+// for a request key is the Respond result, the Fixtures item, or an echo of
+// the request key with a zero status, in that order, and always carries the
+// request uuid and key
+type Server struct {
+	Secret   string                                // passkey secret; empty accepts every request
+	Fixtures map[string]map[string]json.RawMessage // response items by path and request key; see Load
+	Respond  func(path, key string) any            // programmable response item; nil to use Fixtures
+	Latency  time.Duration                         // delay before every response
+	Fail     func(n int64, r *http.Request) int    // http status to fail the nth request with; 0 to serve
+	Reorder  bool                                  // POST: shuffle the response items
+	Partial  int                                   // POST: max response items; 0 for all
+	NDJSON   bool                                  // POST: respond with NDJSON instead of a json array
+
+	URL string // base url; set by Start
+
+	srv      *httptest.Server
+	requests atomic.Int64
+	mu       sync.Mutex
+	keys     []string // requested keys in arrival order
+}
+
+// Start serves the fake node until Close or ctx is done
+func (s *Server) Start(ctx context.Context) *Server {
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{path}/{key...}", s.get)
+	mux.HandleFunc("POST /{path}", s.post)
+
+	var h http.Handler = mux
+	if len(s.Secret) > 0 {
+		h = passkey.NewServer(ctx, s.Secret).IsValid(mux)
+	}
+	s.srv = httptest.NewServer(h)
+	s.URL = s.srv.URL
+	context.AfterFunc(ctx, s.srv.Close)
+
+	return s
+}
+
+// Close shuts down the server
+func (s *Server) Close() { s.srv.Close() }
+
+// Requests returns the number of authenticated requests served
+func (s *Server) Requests() int64 { return s.requests.Load() }
+
+// Keys returns the requested keys in arrival order
+func (s *Server) Keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.keys...)
+}
+
+// Load reads the {path}.json fixture files in dir; each file is a json
+// object of response items keyed by request key
+func (s *Server) Load(dir string) error {
+
+	if s.Fixtures == nil {
+		s.Fixtures = make(map[string]map[string]json.RawMessage)
+	}
+	for path := range Keys {
+		b, err := os.ReadFile(filepath.Join(dir, path+".json"))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		items := make(map[string]json.RawMessage)
+		if err = json.Unmarshal(b, &items); err != nil {
+			return err
+		}
+		s.Fixtures[path] = items
+	}
+
+	return nil
+}
+
+// serve applies the request accounting, latency, and failure injection
+// and reports whether the request should be answered
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) bool {
+
+	if _, ok := Keys[r.PathValue("path")]; !ok {
+		http.NotFound(w, r)
+		return false
+	}
+
+	n := s.requests.Add(1)
+	if s.Latency > 0 {
+		select {
+		case <-time.After(s.Latency):
+		case <-r.Context().Done():
+			return false
+		}
+	}
+	if s.Fail != nil {
+		if status := s.Fail(n, r); status != 0 {
+			w.WriteHeader(status)
+			return false
+		}
+	}
+
+	return true
+}
+
+// get serves GET /{path}/{key}
+func (s *Server) get(w http.ResponseWriter, r *http.Request) {
+
+	if !s.serve(w, r) {
+		return
+	}
+
+	path, key := r.PathValue("path"), r.PathValue("key")
+	s.record(key)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.item(path, key, 0))
+}
+
+// post serves POST /{path} with a text, json, or NDJSON request body
+func (s *Server) post(w http.ResponseWriter, r *http.Request) {
+
+	b, err := decode(r.Header.Get("Content-Encoding"), r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	body := bytes.NewReader(b)
+
+	path := r.PathValue("path")
+	type request struct {
+		uuid uint64
+		key  string
+	}
+	var requests []request
+	read := func(raw json.RawMessage) {
+		var item map[string]any
+		json.Unmarshal(raw, &item)
+		uuid, _ := item["uuid"].(float64)
+		key, _ := item[Keys[path]].(string)
+		requests = append(requests, request{uuid: uint64(uuid), key: key})
+	}
+
+	switch {
+	case strings.HasPrefix(r.Header.Get("Content-Type"), "application/json"):
+		var items []json.RawMessage
+		if err := json.NewDecoder(body).Decode(&items); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for i := range items {
+			read(items[i])
+		}
+	case strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-ndjson"):
+		dec := json.NewDecoder(body)
+		for {
+			var item json.RawMessage
+			if err := dec.Decode(&item); err == io.EOF {
+				break
+			} else if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			read(item)
+		}
+	default:
+		scan := bufio.NewScanner(body)
+		for scan.Scan() {
+			if key := strings.TrimSpace(scan.Text()); len(key) > 0 {
+				requests = append(requests, request{key: key})
+			}
+		}
+	}
+
+	if !s.serve(w, r) {
+		return
+	}
+
+	items := make([]map[string]any, len(requests))
+	for i := range requests {
+		s.record(requests[i].key)
+		items[i] = s.item(path, requests[i].key, requests[i].uuid)
+	}
+	if s.Reorder {
+		rand.Shuffle(len(items), func(i, j int) { items[i], items[j] = items[j], items[i] })
+	}
+	if s.Partial > 0 && len(items) > s.Partial {
+		items = items[:s.Partial]
+	}
+
+	if s.NDJSON {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		enc := json.NewEncoder(w)
+		for i := range items {
+			enc.Encode(items[i])
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(items)
+}
+
+// record appends the requested key
+func (s *Server) record(key string) {
+	s.mu.Lock()
+	s.keys = append(s.keys, key)
+	s.mu.Unlock()
+}
+
+// item returns the response item for the request key
+func (s *Server) item(path, key string, uuid uint64) map[string]any {
+
+	item := make(map[string]any)
+	var raw []byte
+	if s.Respond != nil {
+		raw, _ = json.Marshal(s.Respond(path, key))
+	} else if fixture, ok := s.Fixtures[path][key]; ok {
+		raw = fixture
+	}
+	if len(raw) > 0 {
+		json.Unmarshal(raw, &item)
+	}
+	if item == nil {
+		item = make(map[string]any) // Respond returned nil
+	}
+
+	item[Keys[path]] = key
+	if uuid != 0 {
+		item["uuid"] = uuid
+	}
+
+	return item
+}
@@ -0,0 +1,212 @@
+package fake_test
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zxdev/client/worker/client"
+	"github.com/zxdev/client/worker/fake"
+	"github.com/zxdev/client/worker/job"
+	"github.com/zxdev/passkey"
+)
+
+const secret = "NETSTARXNETSTARXXNETSTARXNETSTAR"
+
+// go test -v worker/fake/fake_test.go --run=FAKE
+func TestFAKE(t *testing.T) {
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "dns.json"),
+		[]byte(`{"one.com":{"a":["10.0.0.1"]},"bad.com":{"status":404}}`), 0644)
+
+	srv := &fake.Server{Secret: secret, Latency: time.Millisecond}
+	if err := srv.Load(dir); err != nil {
+		t.Fatal(err)
+	}
+	srv.Start(t.Context())
+	defer srv.Close()
+
+	// GET and POST with fixtures; a request without a fixture is echoed
+	for size := range 2 {
+		var work = client.Worker{
+			Host:       srv.URL,
+			AuthHeader: passkey.NewClient(t.Context(), secret).SetHeader,
+			Path:       "dns",
+			Size:       size + 1,
+		}
+		work.Connect(t.Context())
+		go func() {
+			defer work.Done()
+			for _, host := range []string{"one.com", "bad.com", "two.com"} {
+				work.Inbox <- job.NewDNS(host)
+			}
+		}()
+		for j := range work.Outbox {
+			r := j.Unpack().(job.DNS)
+			switch r.Host {
+			case "one.com":
+				if !j.Okay() || len(r.A) != 1 || r.A[0] != "10.0.0.1" {
+					t.Fatal("expected fixture", r)
+				}
+			case "bad.com":
+				if j.Okay() {
+					t.Fatal("expected fixture status", r)
+				}
+			default:
+				if !j.Okay() {
+					t.Fatal("expected echo", r)
+				}
+			}
+		}
+	}
+
+	// unauthenticated requests are rejected
+	var anon = client.Worker{Host: srv.URL, AuthHeader: func(*http.Request) {}, Path: "dns"}
+	if _, err := anon.Connect(t.Context()).Do(t.Context(), job.NewDNS("one.com")); err == nil {
+		t.Fatal("expected authentication failure")
+	}
+	anon.Done()
+}
+
+// go test -v worker/fake/fake_test.go --run=INJECT
+func TestINJECT(t *testing.T) {
+
+	// the first request fails with 503, items are reordered, and each
+	// batch response drops its last item
+	srv := (&fake.Server{
+		Secret: secret,
+		Respond: func(path, key string) any {
+			return job.Title{Title: "title " + key}
+		},
+		Fail: func(n int64, _ *http.Request) int {
+			if n == 1 {
+				return http.StatusServiceUnavailable
+			}
+			return 0
+		},
+		Reorder: true,
+		Partial: 2,
+		NDJSON:  true,
+	}).Start(t.Context())
+	defer srv.Close()
+
+	var work = client.Worker{
+		Host:       srv.URL,
+		AuthHeader: passkey.NewClient(t.Context(), secret).SetHeader,
+		Path:       "title",
+		Size:       3,
+		Workers:    1,
+		Retry:      &client.Retry{Base: time.Millisecond},
+		Stream:     true, // NDJSON responses
+	}
+	work.Connect(t.Context())
+	go func() {
+		defer work.Done()
+		for _, url := range []string{"one.com", "two.com", "three.com"} {
+			work.Inbox <- job.NewTitle(url)
+		}
+	}()
+
+	var okay, failed int
+	for j := range work.Outbox {
+		r := j.Unpack().(job.Title)
+		if !j.Okay() {
+			failed++
+			continue
+		}
+		if r.Title != "title "+r.Url {
+			t.Fatal("expected correlated response", r)
+		}
+		okay++
+	}
+	if okay != 2 || failed != 1 {
+		t.Fatal("expected partial batch; got", okay, failed)
+	}
+	if srv.Requests() != 2 || len(srv.Keys()) != 3 {
+		t.Fatal("expected a retry; got", srv.Requests(), srv.Keys())
+	}
+}
+
+// go test -v worker/fake/fake_test.go --run=TRANSPORT
+func TestTRANSPORT(t *testing.T) {
+
+	// record a GET and a json POST session with an odd rdap payload and
+	// replay it without the cluster using a fresh worker
+
+	srv := (&fake.Server{
+		Secret: secret,
+		Respond: func(path, key string) any {
+			return job.Rdap{NRD: true, NameServer: []string{"ns." + key}}
+		},
+		Fail: func(n int64, _ *http.Request) int {
+			if n == 2 {
+				return http.StatusServiceUnavailable
+			}
+			return 0
+		},
+	}).Start(t.Context())
+	defer srv.Close()
+
+	file := filepath.Join(t.TempDir(), "rdap.json")
+	session := func(host string, mode int) (map[string]job.Rdap, error) {
+		rt := &fake.Transport{File: file, Mode: mode}
+		results := make(map[string]job.Rdap)
+		for size := range 2 {
+			var work = client.Worker{
+				Host:   host,
+				Secret: secret,
+				Path:   "rdap",
+				Size:   size + 1,
+				Body:   client.BodyJSON,
+				Retry:  &client.Retry{Base: time.Millisecond},
+				Client: &http.Client{Transport: rt},
+			}
+			work.Connect(t.Context())
+			jobs, err := work.DoBatch(t.Context(), client.Jobs{job.NewRdap("one.com"), job.NewRdap("two.com")})
+			work.Done()
+			if err != nil {
+				return nil, err
+			}
+			for key, j := range jobs {
+				results[strconv.Itoa(size)+key] = j.Unpack().(job.Rdap)
+			}
+		}
+		return results, nil
+	}
+
+	recorded, err := session(srv.URL, fake.Record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := os.ReadFile(file)
+	if strings.Contains(strings.ToLower(string(b)), `"token"`) {
+		t.Fatal("expected the passkey header stripped")
+	}
+
+	served := srv.Requests()
+	replayed, err := session("http://replay.invalid", fake.Replay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if srv.Requests() != served || len(replayed) != 4 {
+		t.Fatal("expected replay without the cluster", srv.Requests(), served, len(replayed))
+	}
+	for key, r := range replayed {
+		if !r.NRD || r.NameServer[0] != recorded[key].NameServer[0] {
+			t.Fatal("expected recorded payload", key, r)
+		}
+	}
+
+	rt := &fake.Transport{File: file, Mode: fake.Replay}
+	var work = client.Worker{Host: "http://replay.invalid", Secret: secret, Path: "rdap", Client: &http.Client{Transport: rt}}
+	if _, err = work.Connect(t.Context()).Do(t.Context(), job.NewRdap("three.com")); !errors.Is(err, fake.ErrNoFixture) {
+		t.Fatal("expected no fixture", err)
+	}
+	work.Done()
+}
@@ -0,0 +1,265 @@
+package fake
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Transport modes
+const (
+	Record = iota // send to the cluster and record every exchange
+	Replay        // serve the recorded exchanges without a cluster
+)
+
+// ErrNoFixture is the Replay failure for a request without a recorded
+// exchange
+var ErrNoFixture = errors.New("fake: no recorded exchange")
+
+// Strip is the default set of request and response headers left out of the
+// recorded exchanges; the passkey token header and any credentials
+var Strip = []string{"Token", "Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+// Exchange is a recorded worker request and its response; the request and
+// response bodies are stored decoded so the fixture file is readable and
+// the replayed responses are sent without a Content-Encoding
+type Exchange struct {
+	Method   string      `json:"method"`
+	Path     string      `json:"path"`             // url path and query; the host is not recorded
+	Header   http.Header `json:"header,omitempty"` // request headers less Strip
+	Body     string      `json:"body,omitempty"`   // request body
+	Status   int         `json:"status"`
+	Response http.Header `json:"response,omitempty"` // response headers less Strip
+	Reply    string      `json:"reply,omitempty"`    // response body
+}
+
+// Transport is a record/replay http.RoundTripper for the worker.Client; in
+// Record mode the exchanges with a real cluster are written to the File
+// fixture after every request and in Replay mode they are served from it
+//
+//	rec := &fake.Transport{File: "testdata/incident.json", Mode: fake.Record}
+//	work := client.Worker{Host: host, Secret: secret, Path: "rdap", Client: &http.Client{Transport: rec}}
+//
+// Replay matches the method, path, and request body, ignoring the job
+// uuids which are rewritten in the response to those of the new request;
+// the matching exchanges are served in recorded order so retried requests
+// replay the recorded failures first, and the last one is repeated
+type Transport struct {
+	File  string            // fixture file; json array of Exchange
+	Mode  int               // Record or Replay
+	Next  http.RoundTripper // Record transport; default http.DefaultTransport
+	Strip []string          // headers left out of the fixture; default Strip
+
+	mu        sync.Mutex
+	exchanges []Exchange
+	used      []bool
+	loaded    bool
+}
+
+// Exchanges returns the recorded or loaded exchanges
+func (t *Transport) Exchanges() []Exchange {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]Exchange(nil), t.exchanges...)
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+
+	body, err := decode(req.Header.Get("Content-Encoding"), req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if t.Mode == Replay {
+		return t.replay(req, body)
+	}
+
+	next := t.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	out := req.Clone(req.Context())
+	out.Body, out.ContentLength = io.NopCloser(bytes.NewReader(body)), int64(len(body))
+	out.Header.Del("Content-Encoding")
+	resp, err := next.RoundTrip(out)
+	if err != nil {
+		return nil, err // transport failures are not recorded
+	}
+	defer resp.Body.Close()
+
+	reply, err := decode(resp.Header.Get("Content-Encoding"), resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	x := Exchange{
+		Method:   req.Method,
+		Path:     req.URL.RequestURI(),
+		Header:   t.strip(req.Header),
+		Body:     string(body),
+		Status:   resp.StatusCode,
+		Response: t.strip(resp.Header),
+		Reply:    string(reply),
+	}
+	x.Header.Del("Content-Encoding")
+	x.Header.Del("Content-Length")
+	x.Response.Del("Content-Encoding")
+	x.Response.Del("Content-Length")
+
+	t.mu.Lock()
+	t.exchanges = append(t.exchanges, x)
+	err = t.save()
+	t.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	return x.response(req, reply), nil
+}
+
+// replay serves the next recorded exchange matching the request
+func (t *Transport) replay(req *http.Request, body []byte) (*http.Response, error) {
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.loaded {
+		b, err := os.ReadFile(t.File)
+		if err != nil {
+			return nil, err
+		}
+		if err = json.Unmarshal(b, &t.exchanges); err != nil {
+			return nil, fmt.Errorf("fake: %s: %w", t.File, err)
+		}
+		t.used, t.loaded = make([]bool, len(t.exchanges)), true
+	}
+
+	path, key := req.URL.RequestURI(), uuids.ReplaceAllString(string(body), `"uuid":0`)
+	match := -1
+	for i := range t.exchanges {
+		x := &t.exchanges[i]
+		if x.Method != req.Method || x.Path != path || uuids.ReplaceAllString(x.Body, `"uuid":0`) != key {
+			continue
+		}
+		if match = i; !t.used[i] {
+			break
+		}
+	}
+	if match < 0 {
+		return nil, fmt.Errorf("%w: %s %s", ErrNoFixture, req.Method, path)
+	}
+	t.used[match] = true
+	x := t.exchanges[match]
+
+	// map the recorded job uuids to the uuids of this request by position
+	recorded, current := uuids.FindAllStringSubmatch(x.Body, -1), uuids.FindAllStringSubmatch(string(body), -1)
+	ids := make(map[string]string, len(recorded))
+	for i := range recorded {
+		ids[recorded[i][1]] = current[i][1]
+	}
+	reply := uuids.ReplaceAllStringFunc(x.Reply, func(s string) string {
+		if id, ok := ids[uuids.FindStringSubmatch(s)[1]]; ok {
+			return `"uuid":` + id
+		}
+		return s
+	})
+
+	return x.response(req, []byte(reply)), nil
+}
+
+// uuids matches the job uuid fields in a request or response body
+var uuids = regexp.MustCompile(`"uuid":\s*(\d+)`)
+
+// response builds the http.Response for the exchange
+func (x *Exchange) response(req *http.Request, body []byte) *http.Response {
+
+	header := x.Response.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	return &http.Response{
+		Status:        strconv.Itoa(x.Status) + " " + http.StatusText(x.Status),
+		StatusCode:    x.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// strip returns a copy of the header without the Strip headers
+func (t *Transport) strip(h http.Header) http.Header {
+
+	h = h.Clone()
+	if h == nil {
+		h = make(http.Header)
+	}
+	strip := t.Strip
+	if strip == nil {
+		strip = Strip
+	}
+	for i := range strip {
+		h.Del(strip[i])
+	}
+
+	return h
+}
+
+// save writes the fixture file; the caller holds t.mu
+func (t *Transport) save() error {
+
+	b, err := json.MarshalIndent(t.exchanges, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := t.File + ".tmp"
+	if err = os.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, t.File)
+}
+
+// decode reads the body and removes the gzip or zstd content coding
+func decode(coding string, body io.ReadCloser) ([]byte, error) {
+
+	if body == nil {
+		return nil, nil
+	}
+	defer body.Close()
+
+	var r io.Reader = body
+	switch coding {
+	case "gzip":
+		zr, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, err
+		}
+		r = zr
+	case "zstd":
+		zr, err := zstd.NewReader(body, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		r = zr
+	case "", "identity":
+	default:
+		return nil, fmt.Errorf("fake: unsupported content coding %q", coding)
+	}
+
+	return io.ReadAll(r)
+}
@@ -93,6 +93,7 @@ func NewCert(a string) *Cert { return &Cert{Host: a} }
 //	  }
 //	}
 type CertJob struct {
+	UUID    uint64       `json:"uuid,omitempty"`    // unique job tracking id
 	Host    string       `json:"host"`              // hostname or hostname:port
 	Port    int          `json:"port,omitempty"`    // port number (defaults to 443)
 	Options *CertOptions `json:"options,omitempty"` // optional expensive checks
@@ -119,11 +120,25 @@ type Cert struct {
 	Verification *VerificationInfo `json:"verification,omitempty"` // certificate verification metadata
 	Certs        []CertificateInfo `json:"certs,omitempty"`        // certificate chain (leaf + intermediates)
 	Revocation   *RevocationInfo   `json:"revocation,omitempty"`   // detailed revocation information (only when include_ocsp option is enabled)
+
+	Options *CertOptions `json:"-"` // request only; optional expensive checks sent via Payload
+
+	err error // client side request failure; see Fail
 }
 
-func (j *Cert) Okay() bool      { return j.Status == 0 }
-func (j *Cert) Request() string { return j.Host }
-func (j *Cert) Unpack() any     { return *j }
+func (j *Cert) Okay() bool                 { return j.Status == 0 }
+func (j *Cert) Request() string            { return j.Host }
+func (j *Cert) Unpack() any                { return *j }
+func (j *Cert) Err() error                 { return j.err }
+func (j *Cert) Fail(status int, err error) { j.Status, j.err = status, err }
+func (j *Cert) ID() uint64                 { return j.UUID }
+func (j *Cert) SetID(id uint64)            { j.UUID = id }
+func (j *Cert) SetRequest(a string)        { j.Host = a }
+
+// Payload is the CertJob POST request item with the Port and Options
+func (j *Cert) Payload() any {
+	return CertJob{UUID: j.UUID, Host: j.Host, Port: j.Port, Options: j.Options}
+}
 
 // ConnectionInfo contains TLS connection metadata
 type ConnectionInfo struct {
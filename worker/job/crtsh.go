@@ -46,11 +46,18 @@ type CRTSH struct {
 	Host   string      `json:"host,omitempty"`   // hostname/domain queried
 	Count  int         `json:"count,omitempty"`  // number of historical certificates found
 	Certs  []CRTSHCert `json:"certs,omitempty"`  // array of historical certificates (sorted by not_before desc)
+
+	err error // client side request failure; see Fail
 }
 
-func (j *CRTSH) Okay() bool      { return j.Status == 0 }
-func (j *CRTSH) Request() string { return j.Host }
-func (j *CRTSH) Unpack() any     { return *j }
+func (j *CRTSH) Okay() bool                 { return j.Status == 0 }
+func (j *CRTSH) Request() string            { return j.Host }
+func (j *CRTSH) Unpack() any                { return *j }
+func (j *CRTSH) Err() error                 { return j.err }
+func (j *CRTSH) Fail(status int, err error) { j.Status, j.err = status, err }
+func (j *CRTSH) ID() uint64                 { return j.UUID }
+func (j *CRTSH) SetID(id uint64)            { j.UUID = id }
+func (j *CRTSH) SetRequest(a string)        { j.Host = a }
 
 // CRTSHCert contains historical certificate data from crt.sh
 type CRTSHCert struct {
@@ -61,11 +61,18 @@ type DNS struct {
 	MX     []string `json:"mx,omitempty"`     // MX records
 	TXT    []string `json:"txt,omitempty"`    // TXT records
 	Domain []string `json:"domain,omitempty"` // rDNS resolution target
+
+	err error // client side request failure; see Fail
 }
 
-func (j *DNS) Okay() bool      { return j.Status == 0 }
-func (j *DNS) Request() string { return j.Host }
-func (j *DNS) Unpack() any     { return *j }
+func (j *DNS) Okay() bool                 { return j.Status == 0 }
+func (j *DNS) Request() string            { return j.Host }
+func (j *DNS) Unpack() any                { return *j }
+func (j *DNS) Err() error                 { return j.err }
+func (j *DNS) Fail(status int, err error) { j.Status, j.err = status, err }
+func (j *DNS) ID() uint64                 { return j.UUID }
+func (j *DNS) SetID(id uint64)            { j.UUID = id }
+func (j *DNS) SetRequest(a string)        { j.Host = a }
 
 // check Rcode response flag
 func HasA(rcode *int) bool      { return *rcode&A != 0 }
@@ -12,8 +12,16 @@ type Firewall struct {
 	Block   bool     `json:"block,omitempty"`   // block flag
 	Version int64    `json:"version,omitempty"` // unix timestamp of last update
 	//Version uint64   `json:"version,omitempty"` // version hash of the current pulled object
+
+	err error // client side request failure; see Fail
 }
 
-func (j *Firewall) Okay() bool      { return j.Status == 0 }
-func (j *Firewall) Request() string { return j.Host }
-func (j *Firewall) Unpack() any     { return *j }
+func (j *Firewall) Okay() bool                 { return j.Status == 0 }
+func (j *Firewall) Request() string            { return j.Host }
+func (j *Firewall) Unpack() any                { return *j }
+func (j *Firewall) Err() error                 { return j.err }
+func (j *Firewall) Fail(status int, err error) { j.Status, j.err = status, err }
+func (j *Firewall) ID() uint64                 { return j.UUID }
+func (j *Firewall) SetID(id uint64)            { j.UUID = id }
+func (j *Firewall) SetRequest(a string)        { j.Host = a }
+func (j *Firewall) Versioned() int64           { return j.Version }
@@ -41,11 +41,18 @@ type Hval struct {
 	Head     []HHeader `json:"head,omitempty"`     // headers
 	N        int       `json:"n,omitempty"`        // n hop|redirect counter; same as len(Head) when > 0 and no method/scheme prefix
 	Security int       `json:"security,omitempty"` // security flags HSTS|CPS|XCTO|ACAO|COOP|CORP|COEP
+
+	err error // client side request failure; see Fail
 }
 
-func (j *Hval) Okay() bool      { return j.Status == 0 }
-func (j *Hval) Request() string { return j.Item }
-func (j *Hval) Unpack() any     { return *j }
+func (j *Hval) Okay() bool                 { return j.Status == 0 }
+func (j *Hval) Request() string            { return j.Item }
+func (j *Hval) Unpack() any                { return *j }
+func (j *Hval) Err() error                 { return j.err }
+func (j *Hval) Fail(status int, err error) { j.Status, j.err = status, err }
+func (j *Hval) ID() uint64                 { return j.UUID }
+func (j *Hval) SetID(id uint64)            { j.UUID = id }
+func (j *Hval) SetRequest(a string)        { j.Item = a }
 
 // SecurityBasic reports true on the minimal valid security combinations of HSTS,CSP
 func SecurityBasic(security *int) bool {
@@ -83,11 +83,18 @@ type Mail struct {
 	Dmarc  []string `json:"dmarc,omitempty"`  // TXT _dmarc.
 	Bimi   []string `json:"bimi,omitempty"`   // TXT <selector>.bimi.
 	Dkim   []string `json:"dkim,omitempty"`   // TXT <selector>._domainkey.
+
+	err error // client side request failure; see Fail
 }
 
-func (j *Mail) Okay() bool      { return j.Status == 0 }
-func (j *Mail) Request() string { return j.Host }
-func (j *Mail) Unpack() any     { return *j }
+func (j *Mail) Okay() bool                 { return j.Status == 0 }
+func (j *Mail) Request() string            { return j.Host }
+func (j *Mail) Unpack() any                { return *j }
+func (j *Mail) Err() error                 { return j.err }
+func (j *Mail) Fail(status int, err error) { j.Status, j.err = status, err }
+func (j *Mail) ID() uint64                 { return j.UUID }
+func (j *Mail) SetID(id uint64)            { j.UUID = id }
+func (j *Mail) SetRequest(a string)        { j.Host = a }
 
 // MailDecode returns a textual represenation of the rCode record types
 //
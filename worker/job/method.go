@@ -28,11 +28,18 @@ type Method struct {
 	Url     string `json:"url,omitempty"`     // url or host target
 	Options bool   `json:"options,omitempty"` // signals non-interrogation of methods
 	Flag    int    `json:"flag,omitempty"`    // method flags and groups
+
+	err error // client side request failure; see Fail
 }
 
-func (j *Method) Okay() bool      { return j.Status == 0 }
-func (j *Method) Request() string { return j.Url }
-func (j *Method) Unpack() any     { return *j }
+func (j *Method) Okay() bool                 { return j.Status == 0 }
+func (j *Method) Request() string            { return j.Url }
+func (j *Method) Unpack() any                { return *j }
+func (j *Method) Err() error                 { return j.err }
+func (j *Method) Fail(status int, err error) { j.Status, j.err = status, err }
+func (j *Method) ID() uint64                 { return j.UUID }
+func (j *Method) SetID(id uint64)            { j.UUID = id }
+func (j *Method) SetRequest(a string)        { j.Url = a }
 
 // MethodStandard reports head,get,post and their combinations as valid for the Standard group
 func MethodStandard(flag *int) bool {
@@ -14,6 +14,7 @@ func NewRdap(a string) *Rdap { return &Rdap{Host: a} }
 //	  "full": true  // Request full RDAP domain object
 //	}
 type RdapJob struct {
+	UUID uint64 `json:"uuid,omitempty"` // unique job tracking id
 	Host string `json:"host"`           // hostname/domain
 	Full bool   `json:"full,omitempty"` // if true, include full RDAP domain object
 }
@@ -30,11 +31,23 @@ type Rdap struct {
 
 	// Full RDAP payload (only populated when full=true in request)
 	Domain *RdapDomain `json:"domain,omitempty"`
+
+	Full bool `json:"-"` // request only; full RDAP domain object sent via Payload
+
+	err error // client side request failure; see Fail
 }
 
-func (j *Rdap) Okay() bool      { return j.Status == 0 }
-func (j *Rdap) Request() string { return j.Host }
-func (j *Rdap) Unpack() any     { return *j }
+func (j *Rdap) Okay() bool                 { return j.Status == 0 }
+func (j *Rdap) Request() string            { return j.Host }
+func (j *Rdap) Unpack() any                { return *j }
+func (j *Rdap) Err() error                 { return j.err }
+func (j *Rdap) Fail(status int, err error) { j.Status, j.err = status, err }
+func (j *Rdap) ID() uint64                 { return j.UUID }
+func (j *Rdap) SetID(id uint64)            { j.UUID = id }
+func (j *Rdap) SetRequest(a string)        { j.Host = a }
+
+// Payload is the RdapJob POST request item with the Full option
+func (j *Rdap) Payload() any { return RdapJob{UUID: j.UUID, Host: j.Host, Full: j.Full} }
 
 // Full RDAP Domain object
 type RdapDomain struct {
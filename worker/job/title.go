@@ -13,8 +13,15 @@ type Title struct {
 	Url    string `json:"url,omitempty"`    // target url or host
 	Title  string `json:"title,omitempty"`  // title extraction
 	Hash   uint64 `json:"hash,omitempty"`   // hash of title
+
+	err error // client side request failure; see Fail
 }
 
-func (j *Title) Okay() bool      { return j.Status == 0 }
-func (j *Title) Request() string { return j.Url }
-func (j *Title) Unpack() any     { return *j }
+func (j *Title) Okay() bool                 { return j.Status == 0 }
+func (j *Title) Request() string            { return j.Url }
+func (j *Title) Unpack() any                { return *j }
+func (j *Title) Err() error                 { return j.err }
+func (j *Title) Fail(status int, err error) { j.Status, j.err = status, err }
+func (j *Title) ID() uint64                 { return j.UUID }
+func (j *Title) SetID(id uint64)            { j.UUID = id }
+func (j *Title) SetRequest(a string)        { j.Url = a }